@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/config"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/csvimport"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/subscription"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+// runImport implements `main import --file=subscriptions.csv [--tenant=<uuid>] [--mapping=...] [--dry-run]`.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_PATH"), "path to config file")
+	tenant := fs.String("tenant", "", "tenant id, the default tenant when empty")
+	file := fs.String("file", "", "path to csv file")
+	mappingRaw := fs.String("mapping", "", "field to column mapping, e.g. service_name=Service,price=Cost")
+	delimiter := fs.String("delimiter", ",", "column delimiter")
+	dryRun := fs.Bool("dry-run", false, "only validate the file")
+	fs.Parse(args)
+
+	if *configPath == "" || *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	mapping, err := csvimport.ParseMapping(*mappingRaw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid mapping:", err)
+		os.Exit(2)
+	}
+	comma, size := utf8.DecodeRuneInString(*delimiter)
+	if size == 0 || size != len(*delimiter) {
+		fmt.Fprintln(os.Stderr, "delimiter should be a single character")
+		os.Exit(2)
+	}
+
+	tenantID := domain.DefaultTenant
+	if *tenant != "" {
+		parsed, err := uuid.Parse(*tenant)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid tenant:", err)
+			os.Exit(2)
+		}
+		tenantID = parsed
+	}
+
+	cfg := config.MustLoadPath(*configPath)
+	log := setupLogger(cfg.Env)
+	if err := runMigrations(cfg); err != nil {
+		log.Error("failed to run migrations", sl.Err(err))
+		os.Exit(1)
+	}
+	db := mustConnectDB(cfg)
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Error("failed to open csv", sl.Err(err))
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	subscriptionInteractor := subscription.NewSubscriptionInteractor(log, psql.NewSubscriptionRepository(db), psql.NewTransactor(db), psql.NewOutboxRepository(db), psql.NewAuditRepository(db))
+	importer := csvimport.NewImporter(log, subscriptionInteractor)
+	report, err := importer.Import(domain.WithActor(domain.WithTenant(context.Background(), tenantID), "cli:import"), f, csvimport.Options{
+		Mapping:   mapping,
+		Delimiter: comma,
+		DryRun:    *dryRun,
+	})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	}
+	if err != nil {
+		if !errors.Is(err, csvimport.ErrInvalidRows) {
+			log.Error("import failed", sl.Err(err))
+		}
+		os.Exit(1)
+	}
+}
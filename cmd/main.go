@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,9 +23,23 @@ import (
 	_ "github.com/immxrtalbeast/subscription-aggregator/cmd/docs"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/config"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/controller"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/controller/middleware"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/jwt"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/slogpretty"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/metrics"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/ratelimit"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/apikey"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/calendar"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/csvimport"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/eventstream"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/outbox"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/receipts"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/statement"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/service/subscription"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/webhook"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -32,9 +51,24 @@ import (
 // @version 1.0
 // @description API для управления подписками
 // @host localhost:8080
-// @BasePath /api/v1
+// @BasePath /api
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		runImport(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ingest-receipts" {
+		runIngestReceipts(os.Args[2:])
+		return
+	}
 	cfg := config.MustLoad()
 
 	log := setupLogger(cfg.Env)
@@ -44,33 +78,186 @@ func main() {
 		panic("fatal")
 	}
 	log.Info("Migrations applied successfully")
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.SSLMode)
-
-	db, err := gorm.Open(gPostgres.New(gPostgres.Config{
-		DSN:                  dsn,
-		PreferSimpleProtocol: true,
-	}), &gorm.Config{
-		SkipDefaultTransaction: true,
-	})
-	if err != nil {
-		panic("failed to connect database")
-	}
+	db := mustConnectDB(cfg)
+	// Background jobs run until stopWorkers, shutdown waits for them before closing db.
+	workers, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workersDone sync.WaitGroup
 
+	transactor := psql.NewTransactor(db)
 	subscriptionRepository := psql.NewSubscriptionRepository(db)
-	subscriptionInteractor := subscription.NewSubscriptionInteractor(log, subscriptionRepository)
+	spanProcessor := mustTracing(cfg.Tracing, log, db)
+	registry := metrics.NewRegistry()
+	mustRegisterMetrics(registry, log, db, subscriptionRepository)
+	outboxRepository := psql.NewOutboxRepository(db)
+	webhookRepository := psql.NewWebhookRepository(db)
+	webhookService := webhook.NewService(log, webhookRepository)
+	subscriptionInteractor := subscription.NewSubscriptionInteractor(log, subscriptionRepository, transactor, outboxRepository, psql.NewAuditRepository(db))
 	subscriptionController := controller.NewSubscriptionController(subscriptionInteractor)
-	router := gin.Default()
-	api := router.Group("/api/v1")
-	api.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	importController := controller.NewImportController(csvimport.NewImporter(log, subscriptionInteractor))
+	calendarTokens := calendar.NewTokenService(log, psql.NewCalendarTokenRepository(db))
+	calendarController := controller.NewCalendarController(calendar.NewFeedGenerator(log, subscriptionInteractor), calendarTokens)
+	statementController := controller.NewStatementController(statement.NewService(log, subscriptionInteractor))
+	catalog, err := loadCatalog(cfg.Receipts.CatalogPath)
+	if err != nil {
+		log.Error("failed to load receipt catalog", sl.Err(err))
+		panic("fatal")
+	}
+	receiptController := controller.NewReceiptController(receipts.NewIngester(log, catalog, subscriptionInteractor, transactor))
+	idempotencyRepository := psql.NewIdempotencyRepository(db, cfg.Idempotency.TTL)
+	idempotency := middleware.Idempotency(log, idempotencyRepository)
+	webhookController := controller.NewWebhookController(webhookService)
+	eventHub := eventstream.NewHub(log, outboxRepository, cfg.Outbox.PollInterval, 256)
+	eventStreamController := controller.NewEventStreamController(eventHub)
+	if cfg.Env != envLocal {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
+	// Handlers pass *gin.Context on as context.Context, values set by middleware on
+	// the request context are only visible through it with the fallback enabled.
+	router.ContextWithFallback = true
+	// Without trusted proxies gin takes the client IP from any X-Forwarded-For header,
+	// which would let clients pick the IP the rate limits are counted for.
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic("invalid trusted proxies: " + err.Error())
+	}
+	router.Use(middleware.Tracing(), middleware.RequestID(log))
+	if cfg.AccessLog.Enabled {
+		router.Use(middleware.AccessLog(log, mustAccessLogPolicy(cfg.AccessLog)))
+	}
+	router.Use(middleware.Metrics(registry), middleware.Recovery(log))
+	router.GET("/api/v1/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(registry))
+	healthController := controller.NewHealthController(psql.NewHealthChecker(db), mustLatestMigration(migrationsDir))
+	router.GET("/healthz", healthController.Live)
+	router.GET("/readyz", healthController.Ready)
+	apiKeyService := apikey.NewService(log, psql.NewAPIKeyRepository(db), transactor, cfg.APIKeys.RotationGrace)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	var store ratelimit.Store
+	var limitIP []gin.HandlerFunc
+	if cfg.RateLimit.Enabled {
+		switch cfg.RateLimit.Store {
+		case "memory":
+			store = ratelimit.NewMemoryStore()
+		case "postgres":
+			rateLimitStore := psql.NewRateLimitStore(db)
+			workersDone.Add(1)
+			go func() {
+				defer workersDone.Done()
+				deleteFullRateLimitBuckets(workers, log, rateLimitStore, time.Hour)
+			}()
+			store = rateLimitStore
+		default:
+			panic("unknown rate limit store: " + cfg.RateLimit.Store)
+		}
+		limitIP = append(limitIP, middleware.RateLimitIP(log, store, mustRateLimit("ip", cfg.RateLimit.IP)))
+	}
+	authenticate := append(slices.Clone(limitIP), middleware.APIKey(log, apiKeyService))
+	if cfg.Auth.Enabled {
+		authenticate = append(authenticate, middleware.Authenticate(log, mustTokenVerifier(cfg.Auth), cfg.Auth.UserIDClaim, cfg.Auth.RolesClaim, cfg.Auth.TenantClaim))
+	} else {
+		log.Warn("authentication is disabled")
+	}
+	authenticate = append(authenticate, middleware.Tenant())
+	if store != nil {
+		authenticate = append(authenticate, middleware.RateLimit(log, store, mustRateLimitPolicy(cfg.RateLimit)))
+	}
+	admin := middleware.RequireScope(domain.ScopeAdmin)
+	read := middleware.RequireScope(domain.ScopeRead)
+	write := middleware.RequireScope(domain.ScopeWrite)
+	reports := middleware.RequireScope(domain.ScopeReports)
+	deprecated := func(successor string) gin.HandlerFunc {
+		return middleware.Deprecated(cfg.APIV1.DeprecatedAt, cfg.APIV1.Sunset, successor)
+	}
+	api := router.Group("/api/v1", authenticate...)
+	{
+		api.POST("/create", deprecated("/api/v2/subscriptions"), write, idempotency, subscriptionController.AddSubcription)
+		api.GET("/:id", deprecated("/api/v2/subscriptions/:id"), read, subscriptionController.Subscription)
+		api.GET("/all", deprecated("/api/v2/subscriptions"), read, subscriptionController.ListSubscription)
+		api.PUT("/update", deprecated("/api/v2/subscriptions"), write, subscriptionController.UpdateSubscription)
+		api.PATCH("/subscriptions/:id", deprecated("/api/v2/subscriptions/:id"), write, subscriptionController.PatchSubscription)
+		api.DELETE("/:id", deprecated("/api/v2/subscriptions/:id"), write, subscriptionController.DeleteSubscription)
+		api.GET("/total", deprecated("/api/v2/reports/total-cost"), reports, subscriptionController.TotalCost)
+	}
+	apiV2 := router.Group("/api/v2", authenticate...)
 	{
-		api.POST("/create", subscriptionController.AddSubcription)
-		api.GET("/:id", subscriptionController.Subscription)
-		api.GET("/all", subscriptionController.ListSubscription)
-		api.PUT("/update", subscriptionController.UpdateSubscription)
-		api.DELETE("/:id", subscriptionController.DeleteSubscription)
-		api.GET("/total", subscriptionController.TotalCost)
+		apiV2.POST("/subscriptions", write, idempotency, subscriptionController.CreateSubscriptionV2)
+		apiV2.GET("/subscriptions", read, subscriptionController.ListSubscription)
+		apiV2.POST("/subscriptions/import", write, importController.ImportCSV)
+		apiV2.POST("/subscriptions/bulk", write, subscriptionController.BulkCreate)
+		apiV2.PUT("/subscriptions/bulk", write, subscriptionController.BulkUpdate)
+		apiV2.DELETE("/subscriptions/bulk", write, subscriptionController.BulkDelete)
+		apiV2.GET("/subscriptions/:id", read, subscriptionController.Subscription)
+		apiV2.PUT("/subscriptions/:id", write, subscriptionController.UpdateSubscriptionV2)
+		apiV2.PATCH("/subscriptions/:id", write, subscriptionController.PatchSubscription)
+		apiV2.DELETE("/subscriptions/:id", write, subscriptionController.DeleteSubscriptionV2)
+		apiV2.POST("/subscriptions/:id/restore", write, subscriptionController.RestoreSubscription)
+		apiV2.GET("/subscriptions/:id/history", read, subscriptionController.SubscriptionHistory)
+		apiV2.GET("/subscriptions/export", read, subscriptionController.ExportSubscriptions)
+		apiV2.GET("/subscriptions/events", read, eventStreamController.Stream)
+		apiV2.GET("/reports/total-cost", reports, subscriptionController.TotalCost)
+		apiV2.GET("/reports/total-cost/export", reports, subscriptionController.ExportTotalCost)
+		apiV2.POST("/users/:user_id/calendar/token", read, calendarController.IssueToken)
+		apiV2.DELETE("/users/:user_id/calendar/token", read, calendarController.RevokeToken)
+		apiV2.POST("/users/:user_id/statements/analyze", read, statementController.Analyze)
+		apiV2.POST("/users/:user_id/statements/confirm", write, statementController.Confirm)
+		apiV2.POST("/users/:user_id/receipts", write, receiptController.Ingest)
+		apiV2.POST("/webhooks", admin, webhookController.RegisterEndpoint)
+		apiV2.GET("/webhooks", admin, webhookController.ListEndpoints)
+		apiV2.DELETE("/webhooks/:id", admin, webhookController.DeleteEndpoint)
+		apiV2.GET("/webhooks/:id/deliveries", admin, webhookController.ListDeliveries)
+		apiV2.POST("/api-keys", admin, apiKeyController.Issue)
+		apiV2.GET("/api-keys", admin, apiKeyController.List)
+		apiV2.POST("/api-keys/:id/rotate", admin, apiKeyController.Rotate)
+		apiV2.DELETE("/api-keys/:id", admin, apiKeyController.Revoke)
+	}
+	// Calendar clients cannot send headers, the feed is authenticated with its token.
+	router.GET("/api/v2/users/:user_id/calendar.ics", slices.Concat(limitIP, []gin.HandlerFunc{
+		middleware.CalendarToken(log, calendarTokens), middleware.Tenant(), calendarController.UserFeed,
+	})...)
+
+	webhookWorker := webhook.NewWorker(log, webhookRepository, webhook.WorkerConfig{
+		PollInterval: cfg.Webhooks.PollInterval,
+		Timeout:      cfg.Webhooks.Timeout,
+		BatchSize:    cfg.Webhooks.BatchSize,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		BaseBackoff:  cfg.Webhooks.BaseBackoff,
+		MaxBackoff:   cfg.Webhooks.MaxBackoff,
+	})
+	relay := outbox.NewRelay(log, outboxRepository, transactor, outbox.RelayConfig{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		Retention:    cfg.Outbox.Retention,
+	}, mustOutboxSinks(cfg.Outbox, log, webhookService)...)
+	workersDone.Add(5)
+	if spanProcessor != nil {
+		workersDone.Add(1)
+		go func() {
+			defer workersDone.Done()
+			spanProcessor.Run(workers)
+		}()
 	}
+	go func() {
+		defer workersDone.Done()
+		webhookWorker.Run(workers)
+	}()
+	go func() {
+		defer workersDone.Done()
+		relay.Run(workers)
+	}()
+	go func() {
+		defer workersDone.Done()
+		purgeIdempotencyKeys(workers, log, idempotencyRepository, time.Hour)
+	}()
+	go func() {
+		defer workersDone.Done()
+		publishEndedSubscriptions(workers, log, subscriptionInteractor, time.Hour)
+	}()
+	go func() {
+		defer workersDone.Done()
+		purgeDeletedSubscriptions(domain.WithActor(workers, "system:purge"), log, subscriptionInteractor, cfg.SoftDelete)
+	}()
+
 	addr := ":" + cfg.Port
 	srv := &http.Server{
 		Addr:         addr,
@@ -79,6 +266,10 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Event streams never end on their own, stopping the hub closes them on shutdown.
+	streams, stopStreams := context.WithCancel(context.Background())
+	srv.RegisterOnShutdown(stopStreams)
+	go eventHub.Run(streams)
 	go func() {
 		log.Info("starting server", "port", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -90,6 +281,16 @@ func main() {
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	<-stop
 	log.Info("shutting down server...")
+	if cfg.Shutdown.Drain > 0 {
+		healthController.Drain()
+		log.Info("draining", slog.Duration("drain", cfg.Shutdown.Drain))
+		select {
+		case <-time.After(cfg.Shutdown.Drain):
+		case <-stop:
+			log.Warn("drain interrupted")
+		}
+	}
+	// The drain doesn't count towards the time given to in-flight requests.
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -97,6 +298,8 @@ func main() {
 		log.Error("server forced to shutdown:", sl.Err(err))
 		panic("fatal")
 	}
+	stopWorkers()
+	workersDone.Wait()
 	sqlDB, _ := db.DB()
 	if err := sqlDB.Close(); err != nil {
 		log.Error("failed to close db: ", sl.Err(err))
@@ -107,6 +310,249 @@ func main() {
 
 }
 
+// purgeIdempotencyKeys deletes expired idempotency keys every interval until ctx is done.
+func purgeIdempotencyKeys(ctx context.Context, log *slog.Logger, repo *psql.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		deleted, err := repo.DeleteExpired(ctx)
+		switch {
+		case err == nil:
+			log.Debug("expired idempotency keys purged", slog.Int64("deleted", deleted))
+		case ctx.Err() == nil:
+			log.Error("failed to purge idempotency keys", sl.Err(err))
+		}
+	}
+}
+
+// deleteFullRateLimitBuckets deletes refilled rate limit buckets every interval until
+// ctx is done.
+func deleteFullRateLimitBuckets(ctx context.Context, log *slog.Logger, store *psql.RateLimitStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		deleted, err := store.DeleteFull(ctx)
+		switch {
+		case err == nil:
+			log.Debug("full rate limit buckets deleted", slog.Int64("deleted", deleted))
+		case ctx.Err() == nil:
+			log.Error("failed to delete full rate limit buckets", sl.Err(err))
+		}
+	}
+}
+
+func mustAccessLogPolicy(cfg config.AccessLogConfig) middleware.AccessLogPolicy {
+	valid := func(rate float64) bool { return rate >= 0 && rate <= 1 }
+	if !valid(cfg.SampleRate) {
+		panic(fmt.Sprintf("invalid access log sample rate: %v", cfg.SampleRate))
+	}
+	for route, rate := range cfg.Routes {
+		if !valid(rate) {
+			panic(fmt.Sprintf("invalid access log sample rate of %s: %v", route, rate))
+		}
+	}
+	return middleware.AccessLogPolicy{
+		SampleRate: cfg.SampleRate,
+		Routes:     cfg.Routes,
+		Exclude:    cfg.Exclude,
+	}
+}
+
+func mustRateLimitPolicy(cfg config.RateLimitConfig) ratelimit.Policy {
+	policy := ratelimit.Policy{
+		Default: mustRateLimit("default", cfg.Default),
+		Routes:  make(map[string]ratelimit.Limit, len(cfg.Routes)),
+	}
+	for route, p := range cfg.Routes {
+		policy.Routes[route] = mustRateLimit(route, p)
+	}
+	return policy
+}
+
+func mustRateLimit(route string, p config.RateLimitPolicy) ratelimit.Limit {
+	if p.Per <= 0 {
+		p.Per = time.Minute
+	}
+	limit := ratelimit.Every(p.Requests, p.Per, p.Burst)
+	if err := limit.Validate(); err != nil {
+		panic("invalid rate limit of " + route + ": " + err.Error())
+	}
+	return limit
+}
+
+// mustTracing sets up the global tracer and returns its span processor, or nil when
+// tracing is disabled.
+func mustTracing(cfg config.TracingConfig, log *slog.Logger, db *gorm.DB) *tracing.BatchProcessor {
+	if !cfg.Enabled {
+		return nil
+	}
+	var exporter tracing.Exporter
+	switch cfg.Exporter {
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName, 10*time.Second)
+	case "stdout":
+		exporter = tracing.NewStdoutExporter(os.Stdout)
+	default:
+		panic("unknown tracing exporter: " + cfg.Exporter)
+	}
+	if err := psql.TraceQueries(db); err != nil {
+		panic("cannot trace queries: " + err.Error())
+	}
+	processor := tracing.NewBatchProcessor(log, exporter, cfg.Interval, cfg.BatchSize)
+	tracing.SetTracer(tracing.NewTracer(processor, cfg.SampleRatio))
+	return processor
+}
+
+func mustOutboxSinks(cfg config.OutboxConfig, log *slog.Logger, webhooks *webhook.Service) []outbox.Sink {
+	sinks := make([]outbox.Sink, 0, len(cfg.Sinks))
+	for _, name := range cfg.Sinks {
+		var publisher domain.EventPublisher
+		switch name {
+		case "webhook":
+			publisher = webhooks
+		case "log":
+			publisher = outbox.NewLogSink(log)
+		case "memory":
+			publisher = outbox.NewBrokerSink(outbox.NewMemoryBroker(), cfg.BrokerSubject)
+		default:
+			panic("unknown outbox sink: " + name)
+		}
+		sinks = append(sinks, outbox.Sink{Name: name, Publisher: publisher})
+	}
+	return sinks
+}
+
+// publishEndedSubscriptions records ended events for the previous month once the
+// month changes, checking every interval.
+func publishEndedSubscriptions(ctx context.Context, log *slog.Logger, subscriptions *subscription.SubscriptionInteractor, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var published domain.MonthYear
+	for {
+		now := time.Now()
+		month := domain.FromTime(time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC))
+		if month != published {
+			_, err := subscriptions.PublishEndedSubscriptions(ctx, month)
+			switch {
+			case err == nil:
+				published = month
+			case ctx.Err() == nil:
+				log.Error("failed to publish ended subscriptions", sl.Err(err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedSubscriptions removes soft-deleted subscriptions once they are older than
+// the retention period.
+func purgeDeletedSubscriptions(ctx context.Context, log *slog.Logger, subscriptions *subscription.SubscriptionInteractor, cfg config.SoftDeleteConfig) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := subscriptions.PurgeDeleted(ctx, cfg.Retention); err != nil && ctx.Err() == nil {
+			log.Error("failed to purge deleted subscriptions", sl.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// mustTokenVerifier builds the JWT verifier from the configured keys.
+func mustTokenVerifier(cfg config.AuthConfig) *jwt.Verifier {
+	opts := jwt.Options{
+		HMACSecret: []byte(cfg.HMACSecret),
+		RSAKeys:    make(map[string]*rsa.PublicKey),
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		Leeway:     cfg.Leeway,
+	}
+	if cfg.JWKSPath != "" {
+		data, err := os.ReadFile(cfg.JWKSPath)
+		if err != nil {
+			panic("cannot read JWKS: " + err.Error())
+		}
+		opts.RSAKeys, err = jwt.ParseJWKS(data)
+		if err != nil {
+			panic("cannot parse JWKS: " + err.Error())
+		}
+	}
+	if cfg.RSAPublicKeyPath != "" {
+		data, err := os.ReadFile(cfg.RSAPublicKeyPath)
+		if err != nil {
+			panic("cannot read RSA public key: " + err.Error())
+		}
+		opts.RSAKeys[""], err = jwt.ParseRSAPublicKey(data)
+		if err != nil {
+			panic("cannot parse RSA public key: " + err.Error())
+		}
+	}
+	if len(opts.HMACSecret) == 0 && len(opts.RSAKeys) == 0 {
+		panic("auth is enabled but no signing keys are configured")
+	}
+	return jwt.NewVerifier(opts)
+}
+
+func mustConnectDB(cfg *config.Config) *gorm.DB {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.SSLMode)
+
+	db, err := gorm.Open(gPostgres.New(gPostgres.Config{
+		DSN:                  dsn,
+		PreferSimpleProtocol: true,
+	}), &gorm.Config{
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	return db
+}
+
+const migrationsDir = "migrations"
+
+// mustLatestMigration returns the highest version among the migration files in dir,
+// the version the schema is at once they are applied.
+func mustLatestMigration(dir string) uint {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		panic("failed to read migrations: " + err.Error())
+	}
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			panic("invalid migration file name: " + entry.Name())
+		}
+		latest = max(latest, uint(version))
+	}
+	if latest == 0 {
+		panic("no migrations in " + dir)
+	}
+	return latest
+}
+
 func runMigrations(cfg *config.Config) error {
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		url.QueryEscape(cfg.DB.User),
@@ -118,7 +564,7 @@ func runMigrations(cfg *config.Config) error {
 	)
 
 	m, err := migrate.New(
-		"file://migrations",
+		"file://"+migrationsDir,
 		dsn,
 	)
 	if err != nil {
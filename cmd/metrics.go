@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/metrics"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+	"gorm.io/gorm"
+)
+
+// mustRegisterMetrics exposes query durations, pool stats of db and subscription totals.
+func mustRegisterMetrics(registry *metrics.Registry, log *slog.Logger, db *gorm.DB, subscriptions *psql.SubscriptionRepository) {
+	queries := registry.Histogram("db_query_duration_seconds", "Database query latency by repository method.", metrics.DefBuckets, "method", "outcome")
+	err := psql.ObserveQueries(db, func(method string, duration time.Duration, err error) {
+		outcome := "ok"
+		if err != nil {
+			outcome = "error"
+		}
+		queries.Observe(duration.Seconds(), method, outcome)
+	})
+	if err != nil {
+		panic("cannot observe queries: " + err.Error())
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic("cannot get sql db: " + err.Error())
+	}
+	stats := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(sqlDB.Stats()) }
+	}
+	registry.GaugeFunc("db_pool_max_open_connections", "Maximum number of open connections.", stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	registry.GaugeFunc("db_pool_open_connections", "Established connections, in use and idle.", stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	registry.GaugeFunc("db_pool_in_use_connections", "Connections currently in use.", stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	registry.GaugeFunc("db_pool_idle_connections", "Idle connections.", stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	registry.CounterFunc("db_pool_wait_total", "Connections waited for.", stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	registry.CounterFunc("db_pool_wait_duration_seconds_total", "Time blocked waiting for a connection.", stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	registry.CounterFunc("db_pool_max_idle_closed_total", "Connections closed due to max_idle_conns.", stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	registry.CounterFunc("db_pool_max_lifetime_closed_total", "Connections closed due to conn_max_lifetime.", stats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+
+	active := registry.Gauge("subscriptions_active", "Subscriptions active in the current month by tenant.", "tenant_id")
+	spend := registry.Gauge("subscriptions_monthly_spend", "Total monthly price of the active subscriptions by tenant.", "tenant_id")
+	registry.OnCollect(func(ctx context.Context) {
+		totals, err := subscriptions.ActiveTotals(ctx, domain.FromTime(time.Now()))
+		if err != nil {
+			log.Error("failed to collect subscription metrics", sl.Err(err))
+			return
+		}
+		active.Reset()
+		spend.Reset()
+		for _, t := range totals {
+			active.Set(float64(t.Subscriptions), t.TenantID.String())
+			spend.Set(float64(t.MonthlySpend), t.TenantID.String())
+		}
+	})
+}
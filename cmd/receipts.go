@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/config"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/receipts"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/subscription"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+// runIngestReceipts implements `main ingest-receipts --user=<uuid> --file=inbox.mbox [--tenant=<uuid>] [--catalog=catalog.yaml] [--dry-run]`.
+func runIngestReceipts(args []string) {
+	fs := flag.NewFlagSet("ingest-receipts", flag.ExitOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_PATH"), "path to config file")
+	tenant := fs.String("tenant", "", "tenant id, the default tenant when empty")
+	user := fs.String("user", "", "user id")
+	file := fs.String("file", "", "path to mbox or eml file")
+	catalogPath := fs.String("catalog", "", "path to receipt catalog, overrides receipts.catalog_path")
+	dryRun := fs.Bool("dry-run", false, "only report recognized receipts")
+	fs.Parse(args)
+
+	if *configPath == "" || *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	userID, err := uuid.Parse(*user)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid user:", err)
+		os.Exit(2)
+	}
+
+	tenantID := domain.DefaultTenant
+	if *tenant != "" {
+		parsed, err := uuid.Parse(*tenant)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid tenant:", err)
+			os.Exit(2)
+		}
+		tenantID = parsed
+	}
+
+	cfg := config.MustLoadPath(*configPath)
+	log := setupLogger(cfg.Env)
+	if *catalogPath == "" {
+		*catalogPath = cfg.Receipts.CatalogPath
+	}
+	catalog, err := loadCatalog(*catalogPath)
+	if err != nil {
+		log.Error("failed to load receipt catalog", sl.Err(err))
+		os.Exit(1)
+	}
+	if err := runMigrations(cfg); err != nil {
+		log.Error("failed to run migrations", sl.Err(err))
+		os.Exit(1)
+	}
+	db := mustConnectDB(cfg)
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Error("failed to open mailbox", sl.Err(err))
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	transactor := psql.NewTransactor(db)
+	subscriptionInteractor := subscription.NewSubscriptionInteractor(log, psql.NewSubscriptionRepository(db), transactor, psql.NewOutboxRepository(db), psql.NewAuditRepository(db))
+	ingester := receipts.NewIngester(log, catalog, subscriptionInteractor, transactor)
+	report, err := ingester.Ingest(domain.WithActor(domain.WithTenant(context.Background(), tenantID), "cli:ingest-receipts"), userID, f, receipts.Options{DryRun: *dryRun})
+	if err != nil {
+		log.Error("ingestion failed", sl.Err(err))
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+}
+
+func loadCatalog(path string) (*receipts.Catalog, error) {
+	if path == "" {
+		return receipts.DefaultCatalog(), nil
+	}
+	return receipts.LoadCatalog(path)
+}
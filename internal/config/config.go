@@ -3,14 +3,31 @@ package config
 import (
 	"flag"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env  string   `yaml:"env" env-default:"local"`
-	Port string   `yaml:"port" env-default: "8080"`
-	DB   DBConfig `yaml:"db"`
+	Env         string            `yaml:"env" env-default:"local"`
+	Port        string            `yaml:"port" env-default:"8080"`
+	DB          DBConfig          `yaml:"db"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Receipts    ReceiptsConfig    `yaml:"receipts"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Outbox      OutboxConfig      `yaml:"outbox"`
+	SoftDelete  SoftDeleteConfig  `yaml:"soft_delete"`
+	Auth        AuthConfig        `yaml:"auth"`
+	APIKeys     APIKeysConfig     `yaml:"api_keys"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Shutdown    ShutdownConfig    `yaml:"shutdown"`
+	AccessLog   AccessLogConfig   `yaml:"access_log"`
+	APIV1       APIV1Config       `yaml:"api_v1"`
+
+	// TrustedProxies are the addresses or CIDRs of the proxies whose X-Forwarded-For
+	// is used as the client IP, none by default.
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 type DBConfig struct {
@@ -22,6 +39,118 @@ type DBConfig struct {
 	SSLMode  string `yaml:"sslmode"`
 }
 
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env-default:"24h"`
+}
+
+type ReceiptsConfig struct {
+	// CatalogPath is a YAML catalog of receipt senders, the built-in one is used when empty.
+	CatalogPath string `yaml:"catalog_path"`
+}
+
+type WebhooksConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"50"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"10"`
+	BaseBackoff  time.Duration `yaml:"base_backoff" env-default:"30s"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" env-default:"6h"`
+}
+
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	Retention    time.Duration `yaml:"retention" env-default:"168h"`
+	// Sinks lists where events are relayed: webhook, log, memory (in-process broker).
+	Sinks         []string `yaml:"sinks" env-default:"webhook"`
+	BrokerSubject string   `yaml:"broker_subject" env-default:"subscriptions"`
+}
+
+type SoftDeleteConfig struct {
+	// Retention is how long deleted subscriptions can be restored before they are purged.
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+// AuthConfig configures JWT bearer authentication. At least one of HMACSecret,
+// RSAPublicKeyPath and JWKSPath is required when it is enabled.
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// HMACSecret verifies HS256 tokens.
+	HMACSecret string `yaml:"hmac_secret" env:"AUTH_HMAC_SECRET"`
+	// RSAPublicKeyPath is a PEM public key or certificate verifying RS256 tokens without kid.
+	RSAPublicKeyPath string `yaml:"rsa_public_key_path"`
+	// JWKSPath is a JWK Set file verifying RS256 tokens by kid.
+	JWKSPath    string        `yaml:"jwks_path"`
+	Issuer      string        `yaml:"issuer"`
+	Audience    string        `yaml:"audience"`
+	Leeway      time.Duration `yaml:"leeway" env-default:"30s"`
+	UserIDClaim string        `yaml:"user_id_claim" env-default:"sub"`
+	RolesClaim  string        `yaml:"roles_claim" env-default:"roles"`
+	// TenantClaim holds the organization of the user, tokens without it use the default tenant.
+	TenantClaim string `yaml:"tenant_claim" env-default:"tenant_id"`
+}
+
+type APIKeysConfig struct {
+	// RotationGrace is how long a rotated key keeps working next to its replacement.
+	RotationGrace time.Duration `yaml:"rotation_grace" env-default:"24h"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// Store keeps buckets in "memory" of every instance or in "postgres", shared by all of them.
+	Store   string          `yaml:"store" env-default:"memory"`
+	Default RateLimitPolicy `yaml:"default"`
+	// IP limits every IP address across all routes before authentication, so requests
+	// with invalid credentials are limited too.
+	IP RateLimitPolicy `yaml:"ip"`
+	// Routes override Default by "METHOD /route/template", e.g. "GET /api/v1/total".
+	Routes map[string]RateLimitPolicy `yaml:"routes"`
+}
+
+// RateLimitPolicy allows Requests per Per on average and up to Burst at once, Burst
+// is Requests when not set.
+type RateLimitPolicy struct {
+	Requests int           `yaml:"requests" env-default:"300"`
+	Per      time.Duration `yaml:"per" env-default:"1m"`
+	Burst    int           `yaml:"burst" env-default:"60"`
+}
+
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"false"`
+	// Exporter is "otlp" for an OTLP/HTTP collector or "stdout" for local runs.
+	Exporter string `yaml:"exporter" env-default:"stdout"`
+	// Endpoint is the OTLP/HTTP collector, e.g. Jaeger on http://localhost:4318.
+	Endpoint    string        `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT" env-default:"http://localhost:4318"`
+	ServiceName string        `yaml:"service_name" env-default:"subscription-aggregator"`
+	SampleRatio float64       `yaml:"sample_ratio" env-default:"1"`
+	Interval    time.Duration `yaml:"interval" env-default:"5s"`
+	BatchSize   int           `yaml:"batch_size" env-default:"512"`
+}
+
+type ShutdownConfig struct {
+	// Drain is how long /readyz fails before the server stops accepting requests, in
+	// addition to the 30 seconds given to in-flight requests.
+	Drain time.Duration `yaml:"drain" env-default:"5s"`
+}
+
+type AccessLogConfig struct {
+	Enabled bool `yaml:"enabled" env-default:"true"`
+	// SampleRate is the share of requests logged, 5xx responses are always logged.
+	SampleRate float64 `yaml:"sample_rate" env-default:"1"`
+	// Routes override SampleRate by "METHOD /route/template", e.g. "GET /api/v2/subscriptions".
+	Routes map[string]float64 `yaml:"routes"`
+	// Exclude lists path prefixes that are never logged.
+	Exclude []string `yaml:"exclude" env-default:"/api/v1/swagger/,/healthz,/readyz,/metrics"`
+}
+
+type APIV1Config struct {
+	// DeprecatedAt is announced in the Deprecation header of /api/v1 routes.
+	DeprecatedAt time.Time `yaml:"deprecated_at" env-layout:"2006-01-02" env-default:"2026-10-19"`
+	// Sunset is the date /api/v1 is switched off, announced in the Sunset header when set.
+	Sunset time.Time `yaml:"sunset" env-layout:"2006-01-02"`
+}
+
 func MustLoad() *Config {
 	configPath := fetchConfigPath()
 	if configPath == "" {
@@ -0,0 +1,22 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// forbidden answers 403 when err denies access to another user's subscriptions and
+// reports whether it did.
+func forbidden(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, domain.ErrForbidden) {
+		return false
+	}
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":   domain.ErrForbidden.Error(),
+		"details": err.Error(),
+	})
+	return true
+}
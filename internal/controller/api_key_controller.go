@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/apikey"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+type apiKeyManager interface {
+	Issue(ctx context.Context, name string, scopes []domain.Scope, expiresAt *time.Time) (*domain.APIKey, string, error)
+	Rotate(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, string, error)
+	Revoke(ctx context.Context, keyID uuid.UUID) error
+	Keys(ctx context.Context) ([]*domain.APIKey, error)
+}
+
+type APIKeyController struct {
+	keys apiKeyManager
+}
+
+func NewAPIKeyController(keys apiKeyManager) *APIKeyController {
+	return &APIKeyController{keys: keys}
+}
+
+type IssueAPIKeyRequest struct {
+	Name      string         `json:"name" binding:"required" example:"billing"`
+	Scopes    []domain.Scope `json:"scopes" binding:"required" example:"read,reports"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+// IssuedAPIKey is the only response that contains the key itself.
+type IssuedAPIKey struct {
+	*domain.APIKey
+	Key string `json:"key" example:"sak_1a2b3c4d_..."`
+}
+
+// @Summary Выпустить API-ключ
+// @Description Ключ передается в заголовке X-API-Key и возвращается только в этом ответе. Области: read, write, reports, admin.
+// @Accept  json
+// @Param   request body controller.IssueAPIKeyRequest true "Параметры ключа"
+// @Success 201 {object} controller.IssuedAPIKey
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/api-keys [post]
+func (c *APIKeyController) Issue(ctx *gin.Context) {
+	var req IssueAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	key, secret, err := c.keys.Issue(ctx, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		respondAPIKeyError(ctx, "failed to issue api key", err)
+		return
+	}
+	ctx.Header("Location", "/api/v2/api-keys/"+key.ID.String())
+	ctx.JSON(http.StatusCreated, IssuedAPIKey{APIKey: key, Key: secret})
+}
+
+// @Summary Получить список API-ключей
+// @Success 200 {array} domain.APIKey
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/api-keys [get]
+func (c *APIKeyController) List(ctx *gin.Context) {
+	keys, err := c.keys.Keys(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get api keys",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// @Summary Перевыпустить API-ключ
+// @Description Новый ключ получает имя и области старого, старый продолжает работать в течение api_keys.rotation_grace.
+// @Param id path string true "ID ключа"
+// @Success 201 {object} controller.IssuedAPIKey
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/api-keys/{id}/rotate [post]
+func (c *APIKeyController) Rotate(ctx *gin.Context) {
+	keyID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	key, secret, err := c.keys.Rotate(ctx, keyID)
+	if err != nil {
+		respondAPIKeyError(ctx, "failed to rotate api key", err)
+		return
+	}
+	ctx.Header("Location", "/api/v2/api-keys/"+key.ID.String())
+	ctx.JSON(http.StatusCreated, IssuedAPIKey{APIKey: key, Key: secret})
+}
+
+// @Summary Отозвать API-ключ
+// @Param id path string true "ID ключа"
+// @Success 204
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/api-keys/{id} [delete]
+func (c *APIKeyController) Revoke(ctx *gin.Context) {
+	keyID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.keys.Revoke(ctx, keyID); err != nil {
+		respondAPIKeyError(ctx, "failed to revoke api key", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func respondAPIKeyError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, apikey.ErrInvalidParams):
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid api key parameters",
+			"details": err.Error(),
+		})
+	case errors.Is(err, psql.ErrAPIKeyNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "api key not found",
+			"details": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
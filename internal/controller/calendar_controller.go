@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+type calendarFeed interface {
+	WriteUserFeed(ctx context.Context, w io.Writer, userID uuid.UUID) error
+}
+
+type calendarTokens interface {
+	IssueToken(ctx context.Context, userID uuid.UUID) (string, error)
+	RevokeToken(ctx context.Context, userID uuid.UUID) error
+}
+
+type CalendarController struct {
+	feed   calendarFeed
+	tokens calendarTokens
+}
+
+func NewCalendarController(feed calendarFeed, tokens calendarTokens) *CalendarController {
+	return &CalendarController{feed: feed, tokens: tokens}
+}
+
+// IssuedCalendarToken is the only response that contains the feed token itself.
+type IssuedCalendarToken struct {
+	Token string `json:"token" example:"cal_..."`
+	URL   string `json:"url" example:"/api/v2/users/60601fee-2bf1-4721-ae6f-7636e79a0cba/calendar.ics?token=cal_..."`
+}
+
+// @Summary Календарь (iCalendar) списаний и окончаний подписок пользователя
+// @Description Календарные клиенты не передают заголовки, поэтому календарь доступен только по токену из POST /v2/users/{user_id}/calendar/token в параметре token.
+// @Param   user_id path string true "ID пользователя"
+// @Param   token query string true "Токен календаря"
+// @Produce text/calendar
+// @Success 200 {file} file
+// @Router /v2/users/{user_id}/calendar.ics [get]
+func (c *CalendarController) UserFeed(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.Header("Content-Type", "text/calendar; charset=utf-8")
+	ctx.Header("Content-Disposition", `inline; filename="subscriptions.ics"`)
+	ctx.Status(http.StatusOK)
+	if err := c.feed.WriteUserFeed(ctx, ctx.Writer, userID); err != nil {
+		if errors.Is(err, domain.ErrForbidden) {
+			// The feed is buffered and nothing is flushed before the subscriptions are
+			// read, so the calendar headers can still be replaced with the error.
+			ctx.Writer.Header().Del("Content-Type")
+			ctx.Writer.Header().Del("Content-Disposition")
+			forbidden(ctx, err)
+			return
+		}
+		ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+// @Summary Выпустить токен календаря
+// @Description Токен дает только чтение календаря пользователя и возвращается только в этом ответе, предыдущий токен перестает работать.
+// @Param   user_id path string true "ID пользователя"
+// @Success 201 {object} controller.IssuedCalendarToken
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{user_id}/calendar/token [post]
+func (c *CalendarController) IssueToken(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	token, err := c.tokens.IssueToken(ctx, userID)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to issue calendar token",
+			"details": err.Error(),
+		})
+		return
+	}
+	feed := "/api/v2/users/" + userID.String() + "/calendar.ics?" + url.Values{"token": {token}}.Encode()
+	ctx.JSON(http.StatusCreated, IssuedCalendarToken{Token: token, URL: feed})
+}
+
+// @Summary Отозвать токен календаря
+// @Param   user_id path string true "ID пользователя"
+// @Success 204
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{user_id}/calendar/token [delete]
+func (c *CalendarController) RevokeToken(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.tokens.RevokeToken(ctx, userID); err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to revoke calendar token",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+func setETag(ctx *gin.Context, version int) {
+	ctx.Header("ETag", fmt.Sprintf("%q", strconv.Itoa(version)))
+}
+
+// ifMatchVersions reads the versions listed in If-Match, answering 412 when one of them
+// is not a strong ETag issued by setETag. A missing header answers 428, except on /api/v1
+// whose clients predate ETags and keep updating unconditionally.
+func ifMatchVersions(ctx *gin.Context) ([]int, bool) {
+	ifMatch := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if strings.HasPrefix(ctx.FullPath(), "/api/v1/") {
+			return []int{domain.AnyVersion}, true
+		}
+		ctx.JSON(http.StatusPreconditionRequired, gin.H{
+			"error": "If-Match header is required",
+		})
+		return nil, false
+	}
+	if ifMatch == "*" {
+		return []int{domain.AnyVersion}, true
+	}
+	var versions []int
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		version, ok := parseETag(tag)
+		if !ok {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error":   "If-Match doesn`t match current version",
+				"details": "expected strong ETags like \"1\"",
+			})
+			return nil, false
+		}
+		versions = append(versions, version)
+	}
+	return versions, true
+}
+
+func parseETag(tag string) (int, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	return version, err == nil && version > 0
+}
+
+// matchVersions calls apply with the versions from If-Match until one of them is current,
+// the precondition holds when any ETag of the list matches.
+func matchVersions(versions []int, apply func(version int) error) error {
+	err := psql.ErrVersionConflict
+	for _, version := range versions {
+		if err = apply(version); !errors.Is(err, psql.ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/eventstream"
+)
+
+const (
+	streamHeartbeat = 15 * time.Second
+	streamRetry     = 3000
+)
+
+// streamedEvents are the event types sent to the dashboard.
+var streamedEvents = map[domain.EventType]bool{
+	domain.EventSubscriptionCreated:  true,
+	domain.EventSubscriptionUpdated:  true,
+	domain.EventSubscriptionDeleted:  true,
+	domain.EventSubscriptionRestored: true,
+}
+
+type eventHub interface {
+	Subscribe(ctx context.Context, userID *uuid.UUID) (*eventstream.Subscription, error)
+}
+
+type EventStreamController struct {
+	hub eventHub
+}
+
+func NewEventStreamController(hub eventHub) *EventStreamController {
+	return &EventStreamController{hub: hub}
+}
+
+// @Summary Поток изменений подписок (Server-Sent Events)
+// @Description События subscription.created, subscription.updated, subscription.deleted и subscription.restored. Поле id события - номер в журнале, после переподключения поток продолжается с заголовка Last-Event-ID (или параметра last_event_id).
+// @Produce text/event-stream
+// @Param   user_id       query  string false "ID пользователя"
+// @Param   last_event_id query  int    false "Номер последнего полученного события"
+// @Param   Last-Event-ID header int    false "Номер последнего полученного события"
+// @Success 200 {string} string "text/event-stream"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/events [get]
+func (c *EventStreamController) Stream(ctx *gin.Context) {
+	var userID *uuid.UUID
+	if raw := ctx.Query("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "couldn`t parse uuid",
+				"details": err.Error(),
+			})
+			return
+		}
+		userID = &parsed
+	}
+	if principal, ok := domain.PrincipalFromContext(ctx.Request.Context()); ok && !principal.AllUsers(domain.ScopeRead) {
+		if userID != nil && *userID != principal.UserID {
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error": domain.ErrForbidden.Error(),
+			})
+			return
+		}
+		userID = &principal.UserID
+	}
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("last_event_id")
+	}
+	var after int64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || parsed < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid Last-Event-ID",
+			})
+			return
+		}
+		after = parsed
+	}
+
+	sub, err := c.hub.Subscribe(ctx.Request.Context(), userID)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "event stream is unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(ctx.Writer)
+	rc.SetWriteDeadline(time.Time{})
+	ctx.Header("Content-Type", sse.ContentType)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.WriteString("retry: " + strconv.Itoa(streamRetry) + "\n\n")
+	ctx.Writer.Flush()
+
+	tenantID, scoped := domain.TenantFromContext(ctx.Request.Context())
+	send := func(event domain.StreamEvent) error {
+		if !streamedEvents[event.Event.Type] {
+			return nil
+		}
+		if scoped && event.Event.Subscription != nil && event.Event.Subscription.TenantID != tenantID {
+			return nil
+		}
+		err := sse.Encode(ctx.Writer, sse.Event{
+			Id:    strconv.FormatInt(event.Sequence, 10),
+			Event: string(event.Event.Type),
+			Data:  event.Event,
+		})
+		ctx.Writer.Flush()
+		return err
+	}
+	if err := sub.Replay(ctx.Request.Context(), after, send); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := ctx.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if event.Sequence <= after {
+				continue
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+}
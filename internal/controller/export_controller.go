@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/export"
+)
+
+const (
+	exportFlushEvery   = 500
+	exportWriteTimeout = 30 * time.Second
+)
+
+// @Summary Выгрузить подписки в CSV или XLSX
+// @Param   format       query string false "Формат выгрузки" Enums(csv, xlsx) default(csv)
+// @Param   user_id      query string false "ID пользователя"
+// @Param   service_name query string false "Название сервиса"
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {file} file
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/export [get]
+func (c *SubscriptionController) ExportSubscriptions(ctx *gin.Context) {
+	format, ok := exportFormat(ctx)
+	if !ok {
+		return
+	}
+	var filter domain.SubscriptionFilter
+	if raw, ok := ctx.GetQuery("user_id"); ok {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "failed to parse userID",
+				"details": err.Error(),
+			})
+			return
+		}
+		filter.UserID = &userID
+	}
+	if serviceName, ok := ctx.GetQuery("service_name"); ok {
+		filter.ServiceName = &serviceName
+	}
+
+	stream := newExportStream(ctx, format, "subscriptions", "id", "service_name", "price", "user_id", "start_date", "end_date", "version")
+	err := c.subscriptionService.ExportSubscriptions(ctx, filter, func(s *domain.Subscription) error {
+		endDate := ""
+		if s.EndDate != nil {
+			endDate = s.EndDate.String()
+		}
+		return stream.writeRow(s.ID.String(), s.ServiceName, s.Price, s.UserID.String(), s.StartDate.String(), endDate, s.Version)
+	})
+	stream.close(err)
+}
+
+// @Summary Выгрузить разбивку стоимости подписок в CSV или XLSX
+// @Param   format       query string false "Формат выгрузки" Enums(csv, xlsx) default(csv)
+// @Param   group_by     query string false "Группировка" Enums(service_name, user_id, month) default(service_name)
+// @Param   user_id      query string false "ID пользователя"
+// @Param   service_name query string false "Название сервиса"
+// @Param   start_date   query string true  "Начальная дата (MM-YYYY)"
+// @Param   end_date     query string true  "Конечная дата (MM-YYYY)"
+// @Param   include_deleted query bool false "Учитывать удаленные подписки" default(false)
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success 200 {file} file
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/reports/total-cost/export [get]
+func (c *SubscriptionController) ExportTotalCost(ctx *gin.Context) {
+	format, ok := exportFormat(ctx)
+	if !ok {
+		return
+	}
+	groupBy := domain.CostGroup(ctx.DefaultQuery("group_by", string(domain.CostGroupServiceName)))
+	if !groupBy.Valid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "group_by should be service_name, user_id or month",
+		})
+		return
+	}
+	query, ok := bindTotalCostQuery(ctx)
+	if !ok {
+		return
+	}
+	breakdown, err := c.subscriptionService.CostBreakdown(ctx, query.userID, query.serviceName, query.startDate, query.endDate, groupBy, query.includeDeleted)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get cost",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	stream := newExportStream(ctx, format, "total-cost", string(groupBy), "subscriptions", "active_months", "total")
+	total := 0
+	for _, line := range breakdown {
+		total += line.Total
+		if err = stream.writeRow(line.Group, line.Subscriptions, line.ActiveMonths, line.Total); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = stream.writeRow("total", "", "", total)
+	}
+	stream.close(err)
+}
+
+func exportFormat(ctx *gin.Context) (string, bool) {
+	format := ctx.DefaultQuery("format", export.FormatCSV)
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "format should be csv or xlsx",
+		})
+		return "", false
+	}
+	return format, true
+}
+
+// exportStream writes rows straight to the response, flushing periodically and
+// extending the write deadline so large exports outlive the server WriteTimeout.
+// Nothing is sent before the first row, so errors of the export call made before
+// it, such as a denied filter, can still be answered with a JSON error.
+type exportStream struct {
+	ctx     *gin.Context
+	format  string
+	name    string
+	header  []interface{}
+	started bool
+	rc      *http.ResponseController
+	writer  export.Writer
+	err     error
+	rows    int
+}
+
+func newExportStream(ctx *gin.Context, format, name string, header ...interface{}) *exportStream {
+	return &exportStream{ctx: ctx, format: format, name: name, header: header}
+}
+
+// start sends the headers and the header row.
+func (s *exportStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.ctx.Header("Content-Type", export.ContentType(s.format))
+	s.ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.name+"."+s.format))
+	s.ctx.Status(http.StatusOK)
+	s.rc = http.NewResponseController(s.ctx.Writer)
+	s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	s.writer, s.err = export.NewWriter(s.format, s.ctx.Writer)
+	if s.err == nil {
+		s.err = s.writer.WriteRow(s.header...)
+		s.rows++
+	}
+}
+
+func (s *exportStream) writeRow(cells ...interface{}) error {
+	s.start()
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.writer.WriteRow(cells...); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%exportFlushEvery == 0 {
+		s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		s.ctx.Writer.Flush()
+	}
+	return nil
+}
+
+// close finishes the file. A failure before the first row is answered with a JSON
+// error; once headers are sent the truncated body is left to the client and the
+// error is only attached to the gin context.
+func (s *exportStream) close(err error) {
+	if err != nil && !s.started {
+		if forbidden(s.ctx, err) {
+			return
+		}
+		s.ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to export",
+			"details": err.Error(),
+		})
+		return
+	}
+	s.start()
+	if err == nil {
+		err = s.err
+	}
+	if err == nil {
+		err = s.writer.Close()
+	}
+	if err != nil {
+		s.ctx.Error(fmt.Errorf("export interrupted after %d rows: %w", s.rows, err))
+		s.ctx.Abort()
+	}
+}
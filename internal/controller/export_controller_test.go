@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// exportInteractor exports the subscriptions of one user and denies the others, like
+// the interactor does for a caller limited to its own subscriptions.
+type exportInteractor struct {
+	domain.SubscriptionInteractor
+	userID        uuid.UUID
+	subscriptions []*domain.Subscription
+}
+
+func (i *exportInteractor) ExportSubscriptions(_ context.Context, filter domain.SubscriptionFilter, fn func(*domain.Subscription) error) error {
+	if filter.UserID != nil && *filter.UserID != i.userID {
+		return fmt.Errorf("service.subscription.export: %w: subscriptions of another user", domain.ErrForbidden)
+	}
+	for _, subscription := range i.subscriptions {
+		if err := fn(subscription); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serveExport(t *testing.T, interactor domain.SubscriptionInteractor, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v2/subscriptions/export", NewSubscriptionController(interactor).ExportSubscriptions)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestExportSubscriptionsForbidden(t *testing.T) {
+	interactor := &exportInteractor{userID: uuid.New()}
+	w := serveExport(t, interactor, "/api/v2/subscriptions/export?user_id="+uuid.NewString())
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusForbidden, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "" {
+		t.Errorf("Content-Disposition = %q, want none", got)
+	}
+	if !strings.Contains(w.Body.String(), domain.ErrForbidden.Error()) {
+		t.Errorf("body = %q, want the forbidden error", w.Body.String())
+	}
+}
+
+func TestExportSubscriptions(t *testing.T) {
+	userID := uuid.New()
+	tests := []struct {
+		name          string
+		subscriptions []*domain.Subscription
+		wantLines     int
+	}{
+		{name: "empty", wantLines: 1},
+		{
+			name: "rows",
+			subscriptions: []*domain.Subscription{
+				{ID: uuid.New(), ServiceName: "Yandex Plus", Price: 400, UserID: userID, StartDate: domain.MonthYear{Month: 7, Year: 2025}},
+				{ID: uuid.New(), ServiceName: "Okko", Price: 300, UserID: userID, StartDate: domain.MonthYear{Month: 1, Year: 2026}},
+			},
+			wantLines: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interactor := &exportInteractor{userID: userID, subscriptions: tt.subscriptions}
+			w := serveExport(t, interactor, "/api/v2/subscriptions/export?user_id="+userID.String())
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d, body %q", w.Code, http.StatusOK, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="subscriptions.csv"` {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			if len(lines) != tt.wantLines {
+				t.Fatalf("got %d lines, want %d: %q", len(lines), tt.wantLines, w.Body.String())
+			}
+			if !strings.HasPrefix(lines[0], "id,service_name,price") {
+				t.Errorf("header = %q", lines[0])
+			}
+		})
+	}
+}
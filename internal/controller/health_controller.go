@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type healthChecker interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
+
+type HealthController struct {
+	checker   healthChecker
+	migration uint
+	draining  atomic.Bool
+}
+
+// NewHealthController reports the instance as ready while the database answers and its
+// schema is at least at migration, the latest version shipped with the binary. A newer
+// schema is expected during rolling deploys, while new instances migrate ahead of old ones.
+func NewHealthController(checker healthChecker, migration uint) *HealthController {
+	return &HealthController{checker: checker, migration: migration}
+}
+
+// Drain makes readiness fail from now on, so load balancers stop sending requests
+// before the server shuts down.
+func (c *HealthController) Drain() {
+	c.draining.Store(true)
+}
+
+// Live answers as long as the process serves HTTP.
+func (c *HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready checks that the instance can serve requests.
+func (c *HealthController) Ready(ctx *gin.Context) {
+	if c.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+	if err := c.checker.Ping(checkCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"error":   "database is unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	version, dirty, err := c.checker.MigrationVersion(checkCtx)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"error":   "failed to check migrations",
+			"details": err.Error(),
+		})
+		return
+	}
+	if dirty || version < c.migration {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "unavailable",
+			"error":   "migrations are behind the expected version",
+			"details": fmt.Sprintf("expected at least version %d, got %d (dirty: %t)", c.migration, version, dirty),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/csvimport"
+)
+
+const maxImportSize = 10 << 20
+
+type subscriptionImporter interface {
+	Import(ctx context.Context, r io.Reader, opts csvimport.Options) (*csvimport.Report, error)
+}
+
+type ImportController struct {
+	importer subscriptionImporter
+}
+
+func NewImportController(importer subscriptionImporter) *ImportController {
+	return &ImportController{importer: importer}
+}
+
+// @Summary Импорт подписок из CSV
+// @Description Тело запроса - CSV (text/csv) или multipart-форма с полем file. Даты в формате MM-YYYY, YYYY-MM или YYYY-MM-DD.
+// @Accept  text/csv
+// @Accept  multipart/form-data
+// @Param   dry_run   query    bool   false "Только проверить файл, ничего не сохраняя"
+// @Param   mapping   query    string false "Соответствие полей колонкам, например service_name=Сервис,price=Цена"
+// @Param   delimiter query    string false "Разделитель колонок" default(,)
+// @Param   file      formData file   false "CSV файл"
+// @Success 200 {object} csvimport.Report
+// @Failure 403 {object} csvimport.Report
+// @Failure 422 {object} csvimport.Report
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/import [post]
+func (c *ImportController) ImportCSV(ctx *gin.Context) {
+	mapping, err := csvimport.ParseMapping(ctx.Query("mapping"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid mapping",
+			"details": err.Error(),
+		})
+		return
+	}
+	opts := csvimport.Options{Mapping: mapping}
+	if raw := ctx.Query("dry_run"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid dry_run",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if raw := ctx.Query("delimiter"); raw != "" {
+		delimiter, size := utf8.DecodeRuneInString(raw)
+		if size != len(raw) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "delimiter should be a single character",
+			})
+			return
+		}
+		opts.Delimiter = delimiter
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportSize)
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "file is required",
+				"details": err.Error(),
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "failed to open file",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	report, err := c.importer.Import(ctx, body, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrForbidden):
+			ctx.JSON(http.StatusForbidden, report)
+		case errors.Is(err, csvimport.ErrInvalidRows):
+			ctx.JSON(http.StatusUnprocessableEntity, report)
+		case errors.Is(err, csvimport.ErrInvalidCSV):
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid csv",
+				"details": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to import subscriptions",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
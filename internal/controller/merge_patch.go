@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+var jsonNull = []byte("null")
+
+func parseSubscriptionPatch(body []byte) (domain.SubscriptionPatch, error) {
+	var patch domain.SubscriptionPatch
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return patch, errors.New("merge patch should be a JSON object")
+	}
+
+	for name, raw := range fields {
+		isNull := bytes.Equal(bytes.TrimSpace(raw), jsonNull)
+		if isNull && name != "end_date" {
+			return patch, fmt.Errorf("%s cannot be null", name)
+		}
+		switch name {
+		case "service_name":
+			var serviceName string
+			if err := json.Unmarshal(raw, &serviceName); err != nil {
+				return patch, fmt.Errorf("service_name should be a string")
+			}
+			patch.ServiceName = &serviceName
+		case "price":
+			var price float64
+			if err := json.Unmarshal(raw, &price); err != nil {
+				return patch, fmt.Errorf("price should be a number")
+			}
+			if price != math.Trunc(price) || math.Abs(price) > math.MaxInt32 {
+				return patch, fmt.Errorf("price should be a whole number")
+			}
+			intPrice := int(price)
+			patch.Price = &intPrice
+		case "user_id":
+			var userIDRaw string
+			if err := json.Unmarshal(raw, &userIDRaw); err != nil {
+				return patch, fmt.Errorf("user_id should be a string")
+			}
+			userID, err := uuid.Parse(userIDRaw)
+			if err != nil {
+				return patch, fmt.Errorf("invalid user_id: %w", err)
+			}
+			patch.UserID = &userID
+		case "start_date":
+			startDate, err := parseMonthYearField(raw)
+			if err != nil {
+				return patch, fmt.Errorf("invalid start date format: %w", err)
+			}
+			patch.StartDate = &startDate
+		case "end_date":
+			if isNull {
+				patch.ClearEndDate = true
+				continue
+			}
+			endDate, err := parseMonthYearField(raw)
+			if err != nil {
+				return patch, fmt.Errorf("invalid end date format: %w", err)
+			}
+			patch.EndDate = &endDate
+		case "id":
+			return patch, errors.New("id cannot be changed")
+		default:
+			return patch, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	return patch, nil
+}
+
+func parseMonthYearField(raw json.RawMessage) (domain.MonthYear, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return domain.MonthYear{}, errors.New("should be a string")
+	}
+	return domain.ParseMonthYear(s)
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+// AccessLogPolicy selects the requests written to the access log. Paths starting with
+// one of Exclude are skipped, other requests are logged with the probability of their
+// route in Routes, keyed by "METHOD /route/template", or SampleRate. Server errors are
+// logged regardless of sampling.
+type AccessLogPolicy struct {
+	SampleRate float64
+	Routes     map[string]float64
+	Exclude    []string
+}
+
+func (p AccessLogPolicy) excluded(path string) bool {
+	for _, prefix := range p.Exclude {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p AccessLogPolicy) sampled(method, route string) bool {
+	rate, ok := p.Routes[method+" "+route]
+	if !ok {
+		rate = p.SampleRate
+	}
+	return rate >= 1 || rate > 0 && rand.Float64() < rate
+}
+
+// AccessLog writes a line per request with its method, route template, status, latency,
+// response size and caller through the request logger, so it carries the request ID.
+func AccessLog(log *slog.Logger, policy AccessLogPolicy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if policy.excluded(ctx.Request.URL.Path) {
+			ctx.Next()
+			return
+		}
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ctx.Writer.Status()
+		if status < http.StatusInternalServerError && !policy.sampled(ctx.Request.Method, route) {
+			return
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		reqCtx := ctx.Request.Context()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+			slog.String("user", domain.ActorFromContext(reqCtx)),
+		}
+		if err := ctx.Errors.Last(); err != nil {
+			attrs = append(attrs, sl.Err(err))
+		}
+		sl.LoggerFromContext(reqCtx, log).LogAttrs(reqCtx, level, "request", attrs...)
+	}
+}
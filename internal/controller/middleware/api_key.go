@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/apikey"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*domain.APIKey, error)
+}
+
+// APIKey authenticates requests carrying X-API-Key and stores the key as the caller.
+// Requests without the header are left to the bearer token authentication.
+func APIKey(log *slog.Logger, keys APIKeyAuthenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		secret := ctx.GetHeader(apiKeyHeader)
+		if secret == "" {
+			ctx.Next()
+			return
+		}
+		key, err := keys.Authenticate(ctx.Request.Context(), secret)
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid api key",
+			})
+			return
+		}
+		if err != nil {
+			sl.LoggerFromContext(ctx.Request.Context(), log).Error("failed to authenticate api key", sl.Err(err))
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to check api key",
+				"details": err.Error(),
+			})
+			return
+		}
+		reqCtx := domain.WithPrincipal(ctx.Request.Context(), domain.KeyPrincipal(key.Scopes, key.TenantID))
+		reqCtx = domain.WithActor(reqCtx, "api_key:"+key.Prefix)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
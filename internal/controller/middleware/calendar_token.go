@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/calendar"
+)
+
+type CalendarTokenAuthenticator interface {
+	Authenticate(ctx context.Context, userID uuid.UUID, token string) (*domain.CalendarToken, error)
+}
+
+// CalendarToken authenticates calendar feed requests with the token query parameter,
+// calendar clients subscribe to a URL and cannot send headers. The caller may only read
+// the feed of the :user_id the token was issued for, in the tenant of the token.
+func CalendarToken(log *slog.Logger, tokens CalendarTokenAuthenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID, err := uuid.Parse(ctx.Param("user_id"))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "couldn`t parse uuid",
+				"details": err.Error(),
+			})
+			return
+		}
+		token := ctx.Query("token")
+		if token == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "calendar token is required",
+			})
+			return
+		}
+		found, err := tokens.Authenticate(ctx.Request.Context(), userID, token)
+		if errors.Is(err, calendar.ErrInvalidToken) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid calendar token",
+			})
+			return
+		}
+		if err != nil {
+			sl.LoggerFromContext(ctx.Request.Context(), log).Error("failed to authenticate calendar token", sl.Err(err))
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to check calendar token",
+				"details": err.Error(),
+			})
+			return
+		}
+		reqCtx := domain.WithPrincipal(ctx.Request.Context(), domain.FeedPrincipal(found.UserID, found.TenantID))
+		reqCtx = domain.WithActor(reqCtx, "user:"+found.UserID.String())
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
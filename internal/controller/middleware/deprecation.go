@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks a route as deprecated (RFC 9745) and points clients to its successor.
+// Path parameters of the successor, such as :id, are filled from the request. A non-zero
+// sunset is announced in the Sunset header (RFC 8594).
+func Deprecated(since, sunset time.Time, successor string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	segments := strings.Split(successor, "/")
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			ctx.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		url := make([]string, len(segments))
+		for i, segment := range segments {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				segment = ctx.Param(name)
+			}
+			url[i] = segment
+		}
+		ctx.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", strings.Join(url, "/")))
+		ctx.Next()
+	}
+}
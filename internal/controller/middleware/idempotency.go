@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// Idempotency replays the stored response for a repeated Idempotency-Key and rejects
+// a key reused with a different request. Requests without the header pass through.
+func Idempotency(log *slog.Logger, store domain.IdempotencyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(idempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key is too long",
+			})
+			return
+		}
+		if _, ok := domain.PrincipalFromContext(ctx.Request.Context()); ok {
+			// Keys are chosen by clients, so each caller gets its own key space in every tenant.
+			tenantID, _ := domain.TenantFromContext(ctx.Request.Context())
+			scoped := sha256.Sum256([]byte(tenantID.String() + ":" + domain.ActorFromContext(ctx.Request.Context()) + ":" + key))
+			key = hex.EncodeToString(scoped[:])
+		}
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(ctx.Request.Method + " " + ctx.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, created, err := store.Reserve(ctx, key, requestHash)
+		if err != nil {
+			sl.LoggerFromContext(ctx.Request.Context(), log).Error("failed to reserve idempotency key", sl.Err(err))
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to check Idempotency-Key",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !created {
+			replay(ctx, record, requestHash)
+			return
+		}
+
+		release := func() {
+			if err := store.Release(context.WithoutCancel(ctx.Request.Context()), key); err != nil {
+				sl.LoggerFromContext(ctx.Request.Context(), log).Error("failed to release idempotency key", sl.Err(err))
+			}
+		}
+		// A panicking handler leaves no response to store, the key is released so that
+		// a retry runs the request again instead of getting 409 until the key expires.
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				release()
+				panic(recovered)
+			}
+		}()
+		recorder := &bodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		ctx.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+		headers := make(map[string]string, len(replayedHeaders))
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := store.Complete(context.WithoutCancel(ctx.Request.Context()), key, status, headers, recorder.body.Bytes()); err != nil {
+			sl.LoggerFromContext(ctx.Request.Context(), log).Error("failed to store idempotent response", sl.Err(err))
+		}
+	}
+}
+
+func replay(ctx *gin.Context, record *domain.IdempotencyRecord, requestHash string) {
+	if record.RequestHash != requestHash {
+		ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key was already used with a different request",
+		})
+		return
+	}
+	if record.StatusCode == 0 {
+		ctx.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "request with this Idempotency-Key is still in progress",
+		})
+		return
+	}
+	for name, value := range record.ResponseHeaders {
+		ctx.Header(name, value)
+	}
+	ctx.Header("Idempotent-Replayed", "true")
+	ctx.Status(record.StatusCode)
+	ctx.Writer.Write(record.ResponseBody)
+	ctx.Abort()
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *bodyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/metrics"
+)
+
+// Metrics counts requests and their latency by method, route template and status.
+// Requests matching no route are reported with the route "unmatched".
+func Metrics(registry *metrics.Registry) gin.HandlerFunc {
+	labels := []string{"method", "route", "status"}
+	requests := registry.Counter("http_requests_total", "HTTP requests by route and status.", labels...)
+	latency := registry.Histogram("http_request_duration_seconds", "HTTP request latency by route and status.", metrics.DefBuckets, labels...)
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		values := []string{ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status())}
+		requests.Inc(values...)
+		latency.Observe(time.Since(started).Seconds(), values...)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+// Recovery turns a panic in a handler into a 500 response and logs it with the stack
+// through the request logger.
+func Recovery(log *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			sl.LoggerFromContext(ctx.Request.Context(), log).Error("panic while handling request",
+				slog.Any("panic", recovered),
+				slog.String("stack", string(debug.Stack())),
+			)
+			ctx.Error(fmt.Errorf("panic: %v", recovered))
+			if ctx.Writer.Written() {
+				ctx.Abort()
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "internal server error",
+			})
+		}()
+		ctx.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// RequestID takes the request ID from X-Request-ID or generates one, echoes it in the
+// response and stores it in the request context for the audit log, together with a
+// logger that adds it and the trace ID to every line written for the request.
+func RequestID(log *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		ctx.Header(requestIDHeader, requestID)
+
+		reqLog := log.With(slog.String("request_id", requestID))
+		if sc, ok := tracing.SpanContextFromContext(ctx.Request.Context()); ok {
+			reqLog = reqLog.With(slog.String("trace_id", sc.TraceID.String()))
+		}
+		reqCtx := domain.WithRequestID(ctx.Request.Context(), requestID)
+		ctx.Request = ctx.Request.WithContext(sl.ContextWithLogger(reqCtx, reqLog))
+		ctx.Next()
+	}
+}
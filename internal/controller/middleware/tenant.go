@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+const tenantHeader = "X-Tenant-ID"
+
+// Tenant stores the organization of the request in its context, which scopes every
+// repository call. Callers bound to a tenant by their token or API key always work in
+// it, admins without one pick a tenant with X-Tenant-ID and other callers work in the
+// default tenant. Requests without a caller may pick any tenant, they are only possible
+// when authentication is disabled.
+func Tenant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requested := domain.DefaultTenant
+		raw := ctx.GetHeader(tenantHeader)
+		if raw != "" {
+			parsed, err := uuid.Parse(raw)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"error":   "couldn`t parse tenant id",
+					"details": err.Error(),
+				})
+				return
+			}
+			requested = parsed
+		}
+		tenantID := requested
+		if principal, ok := domain.PrincipalFromContext(ctx.Request.Context()); ok {
+			switch {
+			case principal.TenantID != nil:
+				tenantID = *principal.TenantID
+			case !principal.HasScope(domain.ScopeAdmin):
+				tenantID = domain.DefaultTenant
+			}
+		}
+		if raw != "" && requested != tenantID {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   domain.ErrForbidden.Error(),
+				"details": "data of another tenant",
+			})
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(domain.WithTenant(ctx.Request.Context(), tenantID))
+		ctx.Next()
+	}
+}
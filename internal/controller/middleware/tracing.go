@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+)
+
+// Tracing starts a server span for every request, continuing the trace of its W3C
+// traceparent header, and reports 5xx responses as failed.
+func Tracing() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		reqCtx, span := tracing.Start(tracing.Extract(ctx.Request.Context(), ctx.Request.Header), ctx.Request.Method+" "+route,
+			tracing.WithKind(tracing.KindServer),
+			tracing.WithAttributes(
+				tracing.String("http.request.method", ctx.Request.Method),
+				tracing.String("http.route", route),
+				tracing.String("url.path", ctx.Request.URL.Path),
+				tracing.String("client.address", ctx.ClientIP()),
+				tracing.String("user_agent.original", ctx.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			if err := ctx.Errors.Last(); err != nil {
+				span.RecordError(err)
+			} else {
+				span.RecordError(httpError(status))
+			}
+		}
+	}
+}
+
+type httpError int
+
+func (e httpError) Error() string {
+	return http.StatusText(int(e))
+}
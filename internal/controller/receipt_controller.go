@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/receipts"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+const maxMailboxUploadSize = 50 << 20
+
+type receiptIngester interface {
+	Ingest(ctx context.Context, userID uuid.UUID, r io.Reader, opts receipts.Options) (*receipts.Report, error)
+}
+
+type ReceiptController struct {
+	ingester receiptIngester
+}
+
+func NewReceiptController(ingester receiptIngester) *ReceiptController {
+	return &ReceiptController{ingester: ingester}
+}
+
+// @Summary Загрузить чеки из почты
+// @Description Файл mbox или одно письмо .eml (тело запроса или поле file multipart-формы). Для сервисов из каталога отправителей создаются подписки или обновляется цена.
+// @Accept  application/mbox
+// @Accept  message/rfc822
+// @Accept  multipart/form-data
+// @Param   user_id path     string true  "ID пользователя"
+// @Param   dry_run query    bool   false "Только распознать чеки, ничего не сохраняя"
+// @Param   file    formData file   false "Файл mbox или eml"
+// @Success 200 {object} receipts.Report
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{user_id}/receipts [post]
+func (c *ReceiptController) Ingest(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	var opts receipts.Options
+	if raw := ctx.Query("dry_run"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid dry_run",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxMailboxUploadSize)
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "file is required",
+				"details": err.Error(),
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "failed to open file",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	report, err := c.ingester.Ingest(ctx, userID, body, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, receipts.ErrInvalidMailbox):
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid mailbox",
+				"details": err.Error(),
+			})
+		case errors.Is(err, psql.ErrVersionConflict):
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":   "subscription was modified during ingestion",
+				"details": err.Error(),
+			})
+		case errors.Is(err, domain.ErrForbidden):
+			forbidden(ctx, err)
+		case errors.Is(err, domain.ErrInvalidSubscription):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "receipt produced an invalid subscription",
+				"details": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed to ingest receipts",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/statement"
+)
+
+const maxStatementUploadSize = 20 << 20
+
+type statementAnalyzer interface {
+	Analyze(ctx context.Context, userID uuid.UUID, r io.Reader, format string, csvOpts statement.CSVOptions) ([]statement.Proposal, error)
+	Confirm(ctx context.Context, userID uuid.UUID, proposals []statement.Proposal) ([]domain.BulkResult, error)
+}
+
+type StatementController struct {
+	statements statementAnalyzer
+}
+
+func NewStatementController(statements statementAnalyzer) *StatementController {
+	return &StatementController{statements: statements}
+}
+
+type ConfirmProposalsRequest struct {
+	Proposals []statement.Proposal `json:"proposals" binding:"required"`
+}
+
+// @Summary Найти регулярные списания в банковской выписке
+// @Description Выписка в формате CSV, OFX или ISO 20022 camt.053 (тело запроса или поле file multipart-формы). Подписки не создаются, результат нужно подтвердить.
+// @Accept  text/csv
+// @Accept  application/xml
+// @Accept  multipart/form-data
+// @Param   user_id   path     string true  "ID пользователя"
+// @Param   format    query    string false "Формат выписки, по умолчанию определяется автоматически" Enums(csv, ofx, camt053)
+// @Param   mapping   query    string false "Колонки CSV, например date=Дата,amount=Сумма,description=Описание"
+// @Param   delimiter query    string false "Разделитель колонок CSV" default(,)
+// @Param   file      formData file   false "Файл выписки"
+// @Success 200 {array} statement.Proposal
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{user_id}/statements/analyze [post]
+func (c *StatementController) Analyze(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	csvOpts, err := statement.ParseCSVMapping(ctx.Query("mapping"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid mapping",
+			"details": err.Error(),
+		})
+		return
+	}
+	if raw := ctx.Query("delimiter"); raw != "" {
+		delimiter, size := utf8.DecodeRuneInString(raw)
+		if size != len(raw) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "delimiter should be a single character",
+			})
+			return
+		}
+		csvOpts.Delimiter = delimiter
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxStatementUploadSize)
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "file is required",
+				"details": err.Error(),
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "failed to open file",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer f.Close()
+		body = f
+	}
+
+	proposals, err := c.statements.Analyze(ctx, userID, body, ctx.Query("format"), csvOpts)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, statement.ErrInvalidStatement) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid statement",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to analyze statement",
+			"details": err.Error(),
+		})
+		return
+	}
+	if proposals == nil {
+		proposals = []statement.Proposal{}
+	}
+	ctx.JSON(http.StatusOK, proposals)
+}
+
+// @Summary Подтвердить найденные в выписке подписки
+// @Accept  json
+// @Param   user_id path string                             true "ID пользователя"
+// @Param   request body controller.ConfirmProposalsRequest true "Выбранные предложения"
+// @Success 201 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/users/{user_id}/statements/confirm [post]
+func (c *StatementController) Confirm(ctx *gin.Context) {
+	userID, err := uuid.Parse(ctx.Param("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	var req ConfirmProposalsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(req.Proposals) == 0 || len(req.Proposals) > domain.MaxBulkItems {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "proposals should not be empty",
+		})
+		return
+	}
+	results, err := c.statements.Confirm(ctx, userID, req.Proposals)
+	items := make([]bulkItemResponse, len(results))
+	positions := make([]int, len(results))
+	for i := range positions {
+		positions[i] = i
+	}
+	mergeBulkResults(items, positions, results, http.StatusCreated)
+	if err != nil {
+		respondBulk(ctx, domain.BulkModeAtomic, items, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{
+		"created": len(results),
+		"results": items,
+	})
+}
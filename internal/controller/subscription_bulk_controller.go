@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+type bulkItemResponse struct {
+	Index   int        `json:"index"`
+	Status  int        `json:"status"`
+	ID      *uuid.UUID `json:"id,omitempty"`
+	Version int        `json:"version,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// @Summary Создать несколько подписок
+// @Description mode=atomic создает все подписки в одной транзакции или ни одной, mode=partial возвращает результат по каждой
+// @Accept  json
+// @Param   request body domain.BulkCreateRequest true "Подписки"
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/bulk [post]
+func (c *SubscriptionController) BulkCreate(ctx *gin.Context) {
+	var req domain.BulkCreateRequest
+	if !bindBulkRequest(ctx, &req, &req.Mode, func() int { return len(req.Items) }) {
+		return
+	}
+	items := make([]bulkItemResponse, len(req.Items))
+	subscriptions := make([]*domain.Subscription, 0, len(req.Items))
+	positions := make([]int, 0, len(req.Items))
+	for i, raw := range req.Items {
+		subscription, err := bulkSubscription(raw)
+		if err != nil {
+			items[i] = bulkItemResponse{Index: i, Status: http.StatusBadRequest, Error: err.Error()}
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+		positions = append(positions, i)
+	}
+	if req.Mode == domain.BulkModeAtomic && len(subscriptions) != len(req.Items) {
+		respondBulk(ctx, req.Mode, abortedBulkItems(items, positions), domain.ErrBulkAborted)
+		return
+	}
+
+	results, err := c.subscriptionService.BulkCreate(ctx, subscriptions, req.Mode)
+	mergeBulkResults(items, positions, results, http.StatusCreated)
+	respondBulk(ctx, req.Mode, items, err)
+}
+
+// @Summary Изменить несколько подписок
+// @Description Каждый элемент содержит version из ETag, mode=atomic откатывает все изменения при первой ошибке
+// @Accept  json
+// @Param   request body domain.BulkUpdateRequest true "Подписки"
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/bulk [put]
+func (c *SubscriptionController) BulkUpdate(ctx *gin.Context) {
+	var req domain.BulkUpdateRequest
+	if !bindBulkRequest(ctx, &req, &req.Mode, func() int { return len(req.Items) }) {
+		return
+	}
+	items := make([]bulkItemResponse, len(req.Items))
+	subscriptions := make([]*domain.Subscription, 0, len(req.Items))
+	positions := make([]int, 0, len(req.Items))
+	for i, raw := range req.Items {
+		subscription, err := bulkUpdateSubscription(raw)
+		if err != nil {
+			items[i] = bulkItemResponse{Index: i, Status: http.StatusBadRequest, Error: err.Error()}
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+		positions = append(positions, i)
+	}
+	if req.Mode == domain.BulkModeAtomic && len(subscriptions) != len(req.Items) {
+		respondBulk(ctx, req.Mode, abortedBulkItems(items, positions), domain.ErrBulkAborted)
+		return
+	}
+
+	results, err := c.subscriptionService.BulkUpdate(ctx, subscriptions, req.Mode)
+	mergeBulkResults(items, positions, results, http.StatusOK)
+	respondBulk(ctx, req.Mode, items, err)
+}
+
+// @Summary Удалить несколько подписок
+// @Accept  json
+// @Param   request body domain.BulkDeleteRequest true "ID и версии подписок"
+// @Success 200 {object} map[string]interface{}
+// @Success 207 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/bulk [delete]
+func (c *SubscriptionController) BulkDelete(ctx *gin.Context) {
+	var req domain.BulkDeleteRequest
+	if !bindBulkRequest(ctx, &req, &req.Mode, func() int { return len(req.Items) }) {
+		return
+	}
+	items := make([]bulkItemResponse, len(req.Items))
+	refs := make([]domain.SubscriptionRef, 0, len(req.Items))
+	positions := make([]int, 0, len(req.Items))
+	for i, raw := range req.Items {
+		if err := binding.Validator.ValidateStruct(raw); err != nil {
+			items[i] = bulkItemResponse{Index: i, Status: http.StatusBadRequest, Error: err.Error()}
+			continue
+		}
+		subscriptionID, err := uuid.Parse(raw.SubscriptionIDRaw)
+		if err != nil {
+			items[i] = bulkItemResponse{Index: i, Status: http.StatusBadRequest, Error: "invalid id: " + err.Error()}
+			continue
+		}
+		refs = append(refs, domain.SubscriptionRef{ID: subscriptionID, Version: raw.Version})
+		positions = append(positions, i)
+	}
+	if req.Mode == domain.BulkModeAtomic && len(refs) != len(req.Items) {
+		respondBulk(ctx, req.Mode, abortedBulkItems(items, positions), domain.ErrBulkAborted)
+		return
+	}
+
+	results, err := c.subscriptionService.BulkDelete(ctx, refs, req.Mode)
+	mergeBulkResults(items, positions, results, http.StatusNoContent)
+	respondBulk(ctx, req.Mode, items, err)
+}
+
+func bindBulkRequest(ctx *gin.Context, req interface{}, mode *domain.BulkMode, count func() int) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return false
+	}
+	if *mode == "" {
+		*mode = domain.BulkModeAtomic
+	}
+	if !mode.Valid() {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "mode should be atomic or partial",
+		})
+		return false
+	}
+	if n := count(); n == 0 || n > domain.MaxBulkItems {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("items should contain from 1 to %d elements", domain.MaxBulkItems),
+		})
+		return false
+	}
+	return true
+}
+
+func bulkSubscription(raw domain.AddSubcriptionRequest) (*domain.Subscription, error) {
+	if err := binding.Validator.ValidateStruct(raw); err != nil {
+		return nil, err
+	}
+	return subscriptionFromRequest(raw)
+}
+
+func bulkUpdateSubscription(raw domain.BulkUpdateItem) (*domain.Subscription, error) {
+	if err := binding.Validator.ValidateStruct(raw); err != nil {
+		return nil, err
+	}
+	subscriptionID, err := uuid.Parse(raw.SubscriptionIDRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id: %w", err)
+	}
+	subscription, err := subscriptionFromRequest(domain.AddSubcriptionRequest{
+		ServiceName:  raw.ServiceName,
+		Price:        raw.Price,
+		UserIDRaw:    raw.UserIDRaw,
+		StartDateRaw: raw.StartDateRaw,
+		EndDateRaw:   raw.EndDateRaw,
+	})
+	if err != nil {
+		return nil, err
+	}
+	subscription.ID = subscriptionID
+	subscription.Version = raw.Version
+	return subscription, nil
+}
+
+func abortedBulkItems(items []bulkItemResponse, positions []int) []bulkItemResponse {
+	for _, i := range positions {
+		items[i] = bulkItemResponse{Index: i, Status: http.StatusFailedDependency, Error: domain.ErrBulkAborted.Error()}
+	}
+	return items
+}
+
+func mergeBulkResults(items []bulkItemResponse, positions []int, results []domain.BulkResult, successStatus int) {
+	for j, result := range results {
+		i := positions[j]
+		item := bulkItemResponse{Index: i, Status: bulkItemStatus(result.Err, successStatus), Version: result.Version}
+		if result.ID != uuid.Nil {
+			item.ID = &result.ID
+		}
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+		}
+		items[i] = item
+	}
+}
+
+func bulkItemStatus(err error, successStatus int) int {
+	switch {
+	case err == nil:
+		return successStatus
+	case errors.Is(err, domain.ErrInvalidSubscription):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, psql.ErrSubscriptNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, psql.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, domain.ErrBulkAborted):
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func respondBulk(ctx *gin.Context, mode domain.BulkMode, items []bulkItemResponse, err error) {
+	if err != nil && !errors.Is(err, domain.ErrBulkAborted) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to process bulk request",
+			"details": err.Error(),
+		})
+		return
+	}
+	failed := 0
+	for _, item := range items {
+		if item.Error != "" {
+			failed++
+		}
+	}
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, domain.ErrBulkAborted):
+		status = http.StatusUnprocessableEntity
+	case failed > 0:
+		status = http.StatusMultiStatus
+	}
+	ctx.JSON(status, gin.H{
+		"mode":      mode,
+		"succeeded": len(items) - failed,
+		"failed":    failed,
+		"results":   items,
+	})
+}
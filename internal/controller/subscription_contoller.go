@@ -2,6 +2,7 @@ package controller
 
 import (
 	"errors"
+	"io"
 	"math"
 	"net/http"
 	"strconv"
@@ -21,9 +22,12 @@ func NewSubscriptionController(subscriptionService domain.SubscriptionInteractor
 }
 
 // @Summary Создать подписку
-// @Param   subscription body domain.AddSubcriptionRequest true "Данные подписки"
+// @Param   Idempotency-Key header string                       false "Ключ идемпотентности для безопасных повторов"
+// @Param   subscription    body   domain.AddSubcriptionRequest true  "Данные подписки"
 // @Success 200 {object} map[string]interface{}
-// @Router /create [post]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/create [post]
 func (c *SubscriptionController) AddSubcription(ctx *gin.Context) {
 
 	var req domain.AddSubcriptionRequest
@@ -76,8 +80,18 @@ func (c *SubscriptionController) AddSubcription(ctx *gin.Context) {
 		})
 		return
 	}
-	subscriptionID, err := c.subscriptionService.AddSubscription(ctx, req.ServiceName, int(req.Price), userID, startDate, endDate)
+	subscription, err := c.subscriptionService.AddSubscription(ctx, req.ServiceName, int(req.Price), userID, startDate, endDate)
 	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSubscription) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid subscription",
+				"details": err.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to create subscription",
 			"details": err.Error(),
@@ -86,14 +100,18 @@ func (c *SubscriptionController) AddSubcription(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, gin.H{
 		"message":         "subscription added successfuly",
-		"subscription_id": subscriptionID,
+		"subscription_id": subscription.ID,
 	})
 }
 
 // @Summary Получить подписку
 // @Param   id path string true "ID подписки"
 // @Success 200 {object} domain.Subscription
-// @Router /{id} [get]
+// @Header  200 {string} ETag "Версия подписки"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/{id} [get]
+// @Router /v2/subscriptions/{id} [get]
 func (c *SubscriptionController) Subscription(ctx *gin.Context) {
 	subscriptionIDRaw := ctx.Param("id")
 	subscriptionID, err := uuid.Parse(subscriptionIDRaw)
@@ -106,6 +124,9 @@ func (c *SubscriptionController) Subscription(ctx *gin.Context) {
 	}
 	subscription, err := c.subscriptionService.Subscription(ctx, subscriptionID)
 	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
 		if errors.Is(err, psql.ErrSubscriptNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{
 				"error": psql.ErrSubscriptNotFound.Error(),
@@ -118,15 +139,19 @@ func (c *SubscriptionController) Subscription(ctx *gin.Context) {
 		})
 		return
 	}
+	setETag(ctx, subscription.Version)
 	ctx.JSON(http.StatusOK, gin.H{
 		"subscription": subscription,
 	})
 }
 
 // @Summary Удалить подписку
-// @Param   id path string true "ID подписки"
+// @Param   id       path   string true "ID подписки"
+// @Param   If-Match header string false "ETag подписки, без него изменение безусловное"
 // @Success 200
-// @Router /{id} [delete]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/{id} [delete]
 func (c *SubscriptionController) DeleteSubscription(ctx *gin.Context) {
 	subscriptionIDRaw := ctx.Param("id")
 	subscriptionID, err := uuid.Parse(subscriptionIDRaw)
@@ -137,13 +162,29 @@ func (c *SubscriptionController) DeleteSubscription(ctx *gin.Context) {
 		})
 		return
 	}
-	if err := c.subscriptionService.DeleteSubscription(ctx, subscriptionID); err != nil {
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	err = matchVersions(versions, func(version int) error {
+		return c.subscriptionService.DeleteSubscription(ctx, subscriptionID, version)
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
 		if errors.Is(err, psql.ErrSubscriptNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{
 				"error": psql.ErrSubscriptNotFound,
 			})
 			return
 		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to delete subscription",
 			"details": err.Error(),
@@ -156,9 +197,12 @@ func (c *SubscriptionController) DeleteSubscription(ctx *gin.Context) {
 }
 
 // @Summary Изменить подписку
-// @Param   subscription body domain.UpdateSubcriptionRequest true "Данные"
+// @Param   If-Match     header string                          false "ETag подписки, без него изменение безусловное"
+// @Param   subscription body   domain.UpdateSubcriptionRequest true "Данные"
 // @Success 200 {object} map[string]interface{}
-// @Router /update [put]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/update [put]
 func (c *SubscriptionController) UpdateSubscription(ctx *gin.Context) {
 
 	var req domain.UpdateSubcriptionRequest
@@ -206,29 +250,139 @@ func (c *SubscriptionController) UpdateSubscription(ctx *gin.Context) {
 		})
 		return
 	}
-	if err = c.subscriptionService.UpdateSubscription(ctx, subscriptionID, req.ServiceName, req.Price, userID, startDate, endDate); err != nil {
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	var subscription *domain.Subscription
+	err = matchVersions(versions, func(version int) (err error) {
+		subscription, err = c.subscriptionService.UpdateSubscription(ctx, subscriptionID, req.ServiceName, req.Price, userID, startDate, endDate, version)
+		return err
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
 		if errors.Is(err, psql.ErrSubscriptNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{
 				"error": psql.ErrSubscriptNotFound,
 			})
 			return
 		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSubscription) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid subscription",
+				"details": err.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "failed to create subscription",
 			"details": err.Error(),
 		})
 		return
 	}
+	setETag(ctx, subscription.Version)
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "subscription updated successfuly",
 	})
 }
 
+// @Summary Частично изменить подписку (JSON Merge Patch, "end_date": null делает подписку бессрочной)
+// @Accept  json
+// @Param   id           path   string                          true "ID подписки"
+// @Param   If-Match     header string                          false "ETag подписки, обязателен в v2"
+// @Param   subscription body   domain.PatchSubscriptionRequest true "Изменяемые поля"
+// @Success 200 {object} domain.Subscription
+// @Header  200 {string} ETag "Новая версия подписки"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/subscriptions/{id} [patch]
+// @Router /v2/subscriptions/{id} [patch]
+func (c *SubscriptionController) PatchSubscription(ctx *gin.Context) {
+	subscriptionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	patch, err := parseSubscriptionPatch(body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid merge patch",
+			"details": err.Error(),
+		})
+		return
+	}
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	var subscription *domain.Subscription
+	err = matchVersions(versions, func(version int) (err error) {
+		subscription, err = c.subscriptionService.PatchSubscription(ctx, subscriptionID, patch, version)
+		return err
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, psql.ErrSubscriptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": psql.ErrSubscriptNotFound.Error(),
+			})
+			return
+		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSubscription) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid subscription",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to patch subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+	setETag(ctx, subscription.Version)
+	ctx.JSON(http.StatusOK, gin.H{
+		"subscription": subscription,
+	})
+}
+
 // @Summary Получить все подписки
 // @Param page query int false "Номер страницы" default(1)
 // @Param limit query int false "Лимит на страницу" default(10)
+// @Param include_deleted query bool false "Включить удаленные подписки" default(false)
 // @Success 200 {object} map[string]interface{}
-// @Router /all [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/all [get]
+// @Router /v2/subscriptions [get]
 func (c *SubscriptionController) ListSubscription(ctx *gin.Context) {
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
@@ -239,10 +393,14 @@ func (c *SubscriptionController) ListSubscription(ctx *gin.Context) {
 		limit = 10
 	}
 	offset := (page - 1) * limit
+	includeDeleted, _ := strconv.ParseBool(ctx.Query("include_deleted"))
 
-	subscriptions, total, err := c.subscriptionService.ListSubscription(ctx, offset, limit)
+	subscriptions, total, err := c.subscriptionService.ListSubscription(ctx, offset, limit, includeDeleted)
 
 	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error":   "failed to get list of subscriptions",
 			"details": err.Error(),
@@ -265,20 +423,55 @@ func (c *SubscriptionController) ListSubscription(ctx *gin.Context) {
 // @Param   service_name query string  false "Название сервиса"
 // @Param   start_date   query string  true  "Начальная дата (MM-YYYY)"
 // @Param   end_date     query string  true  "Конечная дата (MM-YYYY)"
+// @Param   include_deleted query bool false "Учитывать удаленные подписки" default(false)
 // @Success 200 {object} map[string]interface{}
-// @Router /total [get]
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v1/total [get]
+// @Router /v2/reports/total-cost [get]
 func (c *SubscriptionController) TotalCost(ctx *gin.Context) {
+	query, ok := bindTotalCostQuery(ctx)
+	if !ok {
+		return
+	}
+	sum, err := c.subscriptionService.TotalCost(ctx, query.userID, query.serviceName, query.startDate, query.endDate, query.includeDeleted)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get cost",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"total_sum": sum,
+	})
+}
+
+type totalCostQuery struct {
+	userID      *uuid.UUID
+	serviceName *string
+	startDate   domain.MonthYear
+	endDate     domain.MonthYear
+	// includeDeleted counts soft-deleted subscriptions, e.g. for past periods.
+	includeDeleted bool
+}
+
+func bindTotalCostQuery(ctx *gin.Context) (totalCostQuery, bool) {
 	var req struct {
-		UserID      *string `form:"user_id"`
-		ServiceName *string `form:"service_name"`
-		StartDate   string  `form:"start_date" binding:"required"`
-		EndDate     string  `form:"end_date" binding:"required"`
+		UserID         *string `form:"user_id"`
+		ServiceName    *string `form:"service_name"`
+		StartDate      string  `form:"start_date" binding:"required"`
+		EndDate        string  `form:"end_date" binding:"required"`
+		IncludeDeleted bool    `form:"include_deleted"`
 	}
-	if err := ctx.BindQuery(&req); err != nil {
+	if err := ctx.ShouldBindQuery(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return totalCostQuery{}, false
 	}
-	var userID *uuid.UUID
+	query := totalCostQuery{serviceName: req.ServiceName, includeDeleted: req.IncludeDeleted}
 	if req.UserID != nil {
 		id, err := uuid.Parse(*req.UserID)
 		if err != nil {
@@ -286,35 +479,26 @@ func (c *SubscriptionController) TotalCost(ctx *gin.Context) {
 				"error":   "failed to parse userID",
 				"details": err.Error(),
 			})
-			return
+			return totalCostQuery{}, false
 		}
-		userID = &id
+		query.userID = &id
 	}
-	startDate, err := domain.ParseMonthYear(req.StartDate)
+	var err error
+	query.startDate, err = domain.ParseMonthYear(req.StartDate)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error":   "failed to parse start date",
 			"details": err.Error(),
 		})
-		return
+		return totalCostQuery{}, false
 	}
-	endDate, err := domain.ParseMonthYear(req.EndDate)
+	query.endDate, err = domain.ParseMonthYear(req.EndDate)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error":   "failed to parse end date",
 			"details": err.Error(),
 		})
-		return
-	}
-	sum, err := c.subscriptionService.TotalCost(ctx, userID, req.ServiceName, startDate, endDate)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "failed to get cost",
-			"details": err.Error(),
-		})
-		return
+		return totalCostQuery{}, false
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"total_sum": sum,
-	})
+	return query, true
 }
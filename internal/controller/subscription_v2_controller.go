@@ -0,0 +1,322 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+// @Summary Создать подписку
+// @Accept  json
+// @Param   Idempotency-Key header string                       false "Ключ идемпотентности для безопасных повторов"
+// @Param   subscription    body   domain.AddSubcriptionRequest true  "Данные подписки"
+// @Success 201 {object} domain.Subscription
+// @Header  201 {string} Location "URL созданной подписки"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions [post]
+func (c *SubscriptionController) CreateSubscriptionV2(ctx *gin.Context) {
+	req, ok := bindSubscriptionBody(ctx)
+	if !ok {
+		return
+	}
+	subscription, err := c.subscriptionService.AddSubscription(ctx, req.ServiceName, req.Price, req.UserID, req.StartDate, req.EndDate)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSubscription) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid subscription",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to create subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+	setETag(ctx, subscription.Version)
+	ctx.Header("Location", path.Join(ctx.Request.URL.Path, subscription.ID.String()))
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+// @Summary Заменить подписку
+// @Accept  json
+// @Param   id           path   string                       true "ID подписки"
+// @Param   If-Match     header string                       true "ETag подписки"
+// @Param   subscription body   domain.AddSubcriptionRequest true "Данные подписки"
+// @Success 200 {object} domain.Subscription
+// @Header  200 {string} ETag "Новая версия подписки"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/{id} [put]
+func (c *SubscriptionController) UpdateSubscriptionV2(ctx *gin.Context) {
+	subscriptionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	subscription, ok := bindSubscriptionBody(ctx)
+	if !ok {
+		return
+	}
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	var updated *domain.Subscription
+	err = matchVersions(versions, func(version int) (err error) {
+		updated, err = c.subscriptionService.UpdateSubscription(ctx, subscriptionID, subscription.ServiceName, subscription.Price, subscription.UserID, subscription.StartDate, subscription.EndDate, version)
+		return err
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, psql.ErrSubscriptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": psql.ErrSubscriptNotFound.Error(),
+			})
+			return
+		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSubscription) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid subscription",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to update subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+	setETag(ctx, updated.Version)
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// @Summary Удалить подписку
+// @Param   id       path   string true "ID подписки"
+// @Param   If-Match header string true "ETag подписки"
+// @Success 204
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/{id} [delete]
+func (c *SubscriptionController) DeleteSubscriptionV2(ctx *gin.Context) {
+	subscriptionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	err = matchVersions(versions, func(version int) error {
+		return c.subscriptionService.DeleteSubscription(ctx, subscriptionID, version)
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, psql.ErrSubscriptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": psql.ErrSubscriptNotFound.Error(),
+			})
+			return
+		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to delete subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Восстановить удаленную подписку
+// @Param   id       path   string true "ID подписки"
+// @Param   If-Match header string true "ETag удаленной подписки"
+// @Success 200 {object} domain.Subscription
+// @Header  200 {string} ETag "Новая версия подписки"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/{id}/restore [post]
+func (c *SubscriptionController) RestoreSubscription(ctx *gin.Context) {
+	subscriptionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	versions, ok := ifMatchVersions(ctx)
+	if !ok {
+		return
+	}
+	var restored *domain.Subscription
+	err = matchVersions(versions, func(version int) (err error) {
+		restored, err = c.subscriptionService.RestoreSubscription(ctx, subscriptionID, version)
+		return err
+	})
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, psql.ErrSubscriptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": psql.ErrSubscriptNotFound.Error(),
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrNotDeleted) {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error": domain.ErrNotDeleted.Error(),
+			})
+			return
+		}
+		if errors.Is(err, psql.ErrVersionConflict) {
+			ctx.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": psql.ErrVersionConflict.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to restore subscription",
+			"details": err.Error(),
+		})
+		return
+	}
+	setETag(ctx, restored.Version)
+	ctx.JSON(http.StatusOK, restored)
+}
+
+// @Summary История изменений подписки
+// @Param   id    path  string true  "ID подписки"
+// @Param   page  query int    false "Номер страницы" default(1)
+// @Param   limit query int    false "Лимит на страницу" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/subscriptions/{id}/history [get]
+func (c *SubscriptionController) SubscriptionHistory(ctx *gin.Context) {
+	subscriptionID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	entries, total, err := c.subscriptionService.History(ctx, subscriptionID, (page-1)*limit, limit)
+	if err != nil {
+		if forbidden(ctx, err) {
+			return
+		}
+		if errors.Is(err, psql.ErrSubscriptNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error": psql.ErrSubscriptNotFound.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get subscription history",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"history": entries,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+func bindSubscriptionBody(ctx *gin.Context) (*domain.Subscription, bool) {
+	var req domain.AddSubcriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	subscription, err := subscriptionFromRequest(req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid subscription",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	return subscription, true
+}
+
+func subscriptionFromRequest(req domain.AddSubcriptionRequest) (*domain.Subscription, error) {
+	startDate, err := domain.ParseMonthYear(req.StartDateRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date format: %w", err)
+	}
+	var endDate *domain.MonthYear
+	if req.EndDateRaw != "" {
+		parsed, err := domain.ParseMonthYear(req.EndDateRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date format: %w", err)
+		}
+		endDate = &parsed
+	}
+	userID, err := uuid.Parse(req.UserIDRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user_id: %w", err)
+	}
+	return &domain.Subscription{
+		ServiceName: req.ServiceName,
+		Price:       int(req.Price),
+		UserID:      userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+	}, nil
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/service/webhook"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/storage/psql"
+)
+
+type webhookManager interface {
+	RegisterEndpoint(ctx context.Context, rawURL string, eventTypes []domain.EventType, userID *uuid.UUID) (*domain.WebhookEndpoint, error)
+	Endpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, endpointID uuid.UUID) error
+	Deliveries(ctx context.Context, endpointID uuid.UUID, offset, limit int) ([]*domain.WebhookDelivery, int64, error)
+}
+
+type WebhookController struct {
+	webhooks webhookManager
+}
+
+func NewWebhookController(webhooks webhookManager) *WebhookController {
+	return &WebhookController{webhooks: webhooks}
+}
+
+type RegisterWebhookRequest struct {
+	URL        string             `json:"url" binding:"required" example:"https://example.com/hooks/subscriptions"`
+	EventTypes []domain.EventType `json:"event_types" example:"subscription.created,subscription.deleted"`
+	UserIDRaw  string             `json:"user_id" example:"a19df875-4040-4fc3-84ad-003d013fcd89"`
+}
+
+// @Summary Зарегистрировать webhook
+// @Description Пустой event_types - все события, пустой user_id - подписки всех пользователей. Секрет для проверки подписи X-Webhook-Signature возвращается только в этом ответе.
+// @Accept  json
+// @Param   request body controller.RegisterWebhookRequest true "Параметры webhook"
+// @Success 201 {object} domain.WebhookEndpoint
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/webhooks [post]
+func (c *WebhookController) RegisterEndpoint(ctx *gin.Context) {
+	var req RegisterWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	var userID *uuid.UUID
+	if req.UserIDRaw != "" {
+		parsed, err := uuid.Parse(req.UserIDRaw)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "couldn`t parse uuid",
+				"details": err.Error(),
+			})
+			return
+		}
+		userID = &parsed
+	}
+	endpoint, err := c.webhooks.RegisterEndpoint(ctx, req.URL, req.EventTypes, userID)
+	if err != nil {
+		if errors.Is(err, webhook.ErrInvalidEndpoint) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid webhook endpoint",
+				"details": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to register webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.Header("Location", "/api/v2/webhooks/"+endpoint.ID.String())
+	ctx.JSON(http.StatusCreated, endpoint)
+}
+
+// @Summary Получить список webhook
+// @Success 200 {array} domain.WebhookEndpoint
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/webhooks [get]
+func (c *WebhookController) ListEndpoints(ctx *gin.Context) {
+	endpoints, err := c.webhooks.Endpoints(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to get webhooks",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, endpoints)
+}
+
+// @Summary Удалить webhook
+// @Param id path string true "ID webhook"
+// @Success 204
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/webhooks/{id} [delete]
+func (c *WebhookController) DeleteEndpoint(ctx *gin.Context) {
+	endpointID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := c.webhooks.DeleteEndpoint(ctx, endpointID); err != nil {
+		respondWebhookError(ctx, "failed to delete webhook", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// @Summary Журнал доставки webhook
+// @Param id    path  string true  "ID webhook"
+// @Param page  query int    false "Номер страницы" default(1)
+// @Param limit query int    false "Лимит на страницу" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Router /v2/webhooks/{id}/deliveries [get]
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	endpointID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "couldn`t parse uuid",
+			"details": err.Error(),
+		})
+		return
+	}
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	deliveries, total, err := c.webhooks.Deliveries(ctx, endpointID, (page-1)*limit, limit)
+	if err != nil {
+		respondWebhookError(ctx, "failed to get webhook deliveries", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"pagination": gin.H{
+			"page":       page,
+			"limit":      limit,
+			"total":      total,
+			"totalPages": int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+func respondWebhookError(ctx *gin.Context, message string, err error) {
+	if errors.Is(err, psql.ErrWebhookNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "webhook not found",
+			"details": err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey authenticates another service. Only a hash of the key is stored, the key
+// itself is shown once when it is issued. Prefix is its public part that identifies
+// the key in listings and logs.
+type APIKey struct {
+	ID uuid.UUID `json:"id"`
+	// TenantID is the organization the key was issued in, the key only works there.
+	TenantID   uuid.UUID  `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *APIKey, hash string) error
+	APIKey(ctx context.Context, keyID uuid.UUID) (*APIKey, error)
+	// APIKeyByHash returns nil without an error when no key has the hash.
+	APIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error
+	// ExpireAPIKey shortens the lifetime of a key to at, a later expiry is kept.
+	ExpireAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error
+	TouchAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error
+}
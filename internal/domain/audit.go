@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditPatch   AuditOperation = "patch"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+	// AuditPurge is recorded when a soft-deleted subscription is removed for good.
+	AuditPurge AuditOperation = "purge"
+)
+
+// AuditEntry is one change of a subscription. Before is nil for a creation and
+// After is nil for a purge.
+type AuditEntry struct {
+	ID             int64          `json:"id"`
+	TenantID       uuid.UUID      `json:"tenant_id"`
+	SubscriptionID uuid.UUID      `json:"subscription_id"`
+	Operation      AuditOperation `json:"operation"`
+	Actor          string         `json:"actor"`
+	RequestID      string         `json:"request_id,omitempty"`
+	Before         *Subscription  `json:"before"`
+	After          *Subscription  `json:"after"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type AuditRepository interface {
+	AppendAudit(ctx context.Context, entries ...AuditEntry) error
+	// History returns the entries of a subscription, oldest first.
+	History(ctx context.Context, subscriptionID uuid.UUID, offset, limit int) ([]AuditEntry, int64, error)
+}
+
+type actorKey struct{}
+
+type requestIDKey struct{}
+
+// AnonymousActor is recorded for changes made without an authenticated caller.
+const AnonymousActor = "anonymous"
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
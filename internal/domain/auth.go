@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+var ErrForbidden = errors.New("access denied")
+
+// Scope is a permission checked per route. Users get the scopes of their role, API
+// keys the scopes they were issued with.
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"
+	ScopeWrite   Scope = "write"
+	ScopeReports Scope = "reports"
+	// ScopeAdmin grants every other scope and the management endpoints.
+	ScopeAdmin Scope = "admin"
+)
+
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopeReports, ScopeAdmin}
+
+func (s Scope) Valid() bool {
+	for _, known := range Scopes {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Role string
+
+const (
+	// RoleUser manages its own subscriptions and reports on them.
+	RoleUser Role = "user"
+	// RoleAnalyst sees aggregate reports across all users, but no subscriptions.
+	RoleAnalyst Role = "analyst"
+	RoleAdmin   Role = "admin"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleUser:    {ScopeRead, ScopeWrite, ScopeReports},
+	RoleAnalyst: {ScopeReports},
+	RoleAdmin:   {ScopeAdmin},
+}
+
+// RoleOf returns the most privileged known role of roles and RoleUser when there is none.
+func RoleOf(roles []string) Role {
+	for _, role := range []Role{RoleAdmin, RoleAnalyst} {
+		if slices.Contains(roles, string(role)) {
+			return role
+		}
+	}
+	return RoleUser
+}
+
+// Principal is the authenticated caller of a request: a user from a JWT or a service
+// holding an API key.
+type Principal struct {
+	// UserID is uuid.Nil for API keys.
+	UserID uuid.UUID
+	// Role is empty for API keys.
+	Role   Role
+	Scopes []Scope
+	// TenantID is the organization the caller belongs to, nil for users whose token
+	// names none.
+	TenantID *uuid.UUID
+}
+
+func UserPrincipal(userID uuid.UUID, role Role, tenantID *uuid.UUID) Principal {
+	return Principal{UserID: userID, Role: role, Scopes: roleScopes[role], TenantID: tenantID}
+}
+
+// FeedPrincipal is the caller of a calendar feed authenticated with a feed token, it
+// may only read the subscriptions of userID.
+func FeedPrincipal(userID, tenantID uuid.UUID) Principal {
+	return Principal{UserID: userID, Role: RoleUser, Scopes: []Scope{ScopeRead}, TenantID: &tenantID}
+}
+
+func KeyPrincipal(scopes []Scope, tenantID uuid.UUID) Principal {
+	return Principal{Scopes: scopes, TenantID: &tenantID}
+}
+
+// HasScope reports whether the caller may perform operations of scope, the admin
+// scope grants every other one.
+func (p Principal) HasScope(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// AllUsers reports whether the caller may perform operations of scope on subscriptions
+// of every user. API keys serve other services, so they are limited by their scopes
+// instead of a user; analysts are limited to reports.
+func (p Principal) AllUsers(scope Scope) bool {
+	if !p.HasScope(scope) {
+		return false
+	}
+	switch p.Role {
+	case "", RoleAdmin:
+		return true
+	case RoleAnalyst:
+		return scope == ScopeReports
+	}
+	return false
+}
+
+// Authorize fails with ErrForbidden unless the caller of ctx may perform operations of
+// scope on subscriptions of userID. Callers without a principal may do everything.
+func Authorize(ctx context.Context, scope Scope, userID uuid.UUID) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || principal.AllUsers(scope) {
+		return nil
+	}
+	if !principal.HasScope(scope) {
+		return fmt.Errorf("%w: no %s permission", ErrForbidden, scope)
+	}
+	if principal.UserID != userID {
+		return fmt.Errorf("%w: subscriptions of another user", ErrForbidden)
+	}
+	return nil
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller of the request. Calls without a principal
+// come from trusted code such as CLI commands and background jobs.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+const MaxBulkItems = 1000
+
+var ErrBulkAborted = errors.New("bulk operation rolled back")
+
+type BulkMode string
+
+const (
+	// BulkModeAtomic applies every item in one transaction or none of them.
+	BulkModeAtomic BulkMode = "atomic"
+	// BulkModePartial applies items independently and reports a result per item.
+	BulkModePartial BulkMode = "partial"
+)
+
+func (m BulkMode) Valid() bool {
+	return m == BulkModeAtomic || m == BulkModePartial
+}
+
+type SubscriptionRef struct {
+	ID      uuid.UUID
+	Version int
+}
+
+type BulkResult struct {
+	Index   int
+	ID      uuid.UUID
+	Version int
+	Err     error
+}
+
+type BulkCreateRequest struct {
+	Mode  BulkMode                `json:"mode" example:"atomic"`
+	Items []AddSubcriptionRequest `json:"items"`
+}
+
+type BulkUpdateItem struct {
+	SubscriptionIDRaw string  `json:"id" binding:"required"`
+	Version           int     `json:"version" binding:"required" example:"1"`
+	ServiceName       string  `json:"service_name" binding:"required" example:"Yandex Plus"`
+	Price             float64 `json:"price" binding:"required" example:"400"`
+	UserIDRaw         string  `json:"user_id" binding:"required" example:"a19df875-4040-4fc3-84ad-003d013fcd89"`
+	StartDateRaw      string  `json:"start_date" binding:"required" example:"07-2025"`
+	EndDateRaw        string  `json:"end_date" example:"07-2026"`
+}
+
+type BulkUpdateRequest struct {
+	Mode  BulkMode         `json:"mode" example:"partial"`
+	Items []BulkUpdateItem `json:"items"`
+}
+
+type BulkDeleteItem struct {
+	SubscriptionIDRaw string `json:"id" binding:"required"`
+	Version           int    `json:"version" binding:"required" example:"1"`
+}
+
+type BulkDeleteRequest struct {
+	Mode  BulkMode         `json:"mode" example:"atomic"`
+	Items []BulkDeleteItem `json:"items"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarToken lets calendar clients, which cannot send headers, read the .ics feed of
+// one user with the token in the URL. Only a hash of the token is stored, the token
+// itself is shown once when it is issued.
+type CalendarToken struct {
+	TenantID  uuid.UUID `json:"tenant_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CalendarTokenRepository interface {
+	// SaveCalendarToken replaces the token of the user.
+	SaveCalendarToken(ctx context.Context, token *CalendarToken, hash string) error
+	// CalendarTokenByHash returns nil without an error when no token has the hash.
+	CalendarTokenByHash(ctx context.Context, hash string) (*CalendarToken, error)
+	DeleteCalendarToken(ctx context.Context, userID uuid.UUID) error
+}
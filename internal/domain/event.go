@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventSubscriptionCreated EventType = "subscription.created"
+	EventSubscriptionUpdated EventType = "subscription.updated"
+	EventSubscriptionDeleted EventType = "subscription.deleted"
+	// EventSubscriptionRestored is published when a soft-deleted subscription is restored.
+	EventSubscriptionRestored EventType = "subscription.restored"
+	// EventSubscriptionEnded is published once the last month of a subscription is over.
+	EventSubscriptionEnded EventType = "subscription.ended"
+)
+
+var EventTypes = []EventType{
+	EventSubscriptionCreated,
+	EventSubscriptionUpdated,
+	EventSubscriptionDeleted,
+	EventSubscriptionRestored,
+	EventSubscriptionEnded,
+}
+
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a subscription lifecycle event. ID is derived from the subscription state,
+// so publishing the same change twice yields the same ID and consumers can deduplicate.
+type Event struct {
+	ID           string        `json:"id"`
+	Type         EventType     `json:"type"`
+	OccurredAt   time.Time     `json:"occurred_at"`
+	Subscription *Subscription `json:"data"`
+}
+
+func NewSubscriptionEvent(eventType EventType, subscription *Subscription, occurredAt time.Time) Event {
+	id := fmt.Sprintf("%s:%s", eventType, subscription.ID)
+	switch eventType {
+	case EventSubscriptionUpdated, EventSubscriptionDeleted, EventSubscriptionRestored:
+		id = fmt.Sprintf("%s:%d", id, subscription.Version)
+	case EventSubscriptionEnded:
+		if subscription.EndDate != nil {
+			id = fmt.Sprintf("%s:%s", id, subscription.EndDate)
+		}
+	}
+	snapshot := *subscription
+	return Event{
+		ID:           id,
+		Type:         eventType,
+		OccurredAt:   occurredAt.UTC(),
+		Subscription: &snapshot,
+	}
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
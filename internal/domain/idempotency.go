@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord is a stored response for an Idempotency-Key.
+// StatusCode is 0 while the original request is still being processed.
+type IdempotencyRecord struct {
+	Key             string
+	RequestHash     string
+	StatusCode      int
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+	CreatedAt       time.Time
+}
+
+type IdempotencyRepository interface {
+	// Reserve stores an in-progress record for key, or returns the existing one with created == false.
+	Reserve(ctx context.Context, key, requestHash string) (record *IdempotencyRecord, created bool, err error)
+	Complete(ctx context.Context, key string, statusCode int, headers map[string]string, body []byte) error
+	Release(ctx context.Context, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is an event stored in the same transaction as the change it describes.
+type OutboxMessage struct {
+	ID    int64
+	Event Event
+}
+
+type OutboxRepository interface {
+	Append(ctx context.Context, events ...Event) error
+	// ClaimPending returns up to limit unpublished messages in id order and keeps other
+	// relays waiting until the transaction it is called in ends.
+	ClaimPending(ctx context.Context, limit int) ([]OutboxMessage, error)
+	// MarkPublished assigns the next sequence numbers to ids in the given order.
+	MarkPublished(ctx context.Context, ids []int64) error
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+// StreamEvent is a published event and its position in the event log. Sequences are
+// assigned in commit order, so a reader that has seen n never misses an event below n.
+type StreamEvent struct {
+	Sequence int64
+	Event    Event
+}
+
+type EventLog interface {
+	LastSequence(ctx context.Context) (int64, error)
+	// EventsAfter returns up to limit events with a sequence above after, optionally
+	// only the ones of userID, in sequence order.
+	EventsAfter(ctx context.Context, after int64, userID *uuid.UUID, limit int) ([]StreamEvent, error)
+}
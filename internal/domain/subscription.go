@@ -2,36 +2,147 @@ package domain
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	ErrInvalidSubscription = errors.New("invalid subscription")
+	ErrNotDeleted          = errors.New("subscription is not deleted")
+)
+
 type Subscription struct {
-	ID          uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	ID uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey" json:"id"`
+	// TenantID is the organization owning the subscription, it is taken from the request.
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
 	ServiceName string     `gorm:"not null" json:"service_name"`
 	Price       int        `gorm:"not null" json:"price"`
 	UserID      uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
 	StartDate   MonthYear  `gorm:"not null" json:"start_date"`
 	EndDate     *MonthYear `json:"end_date"`
+	Version     int        `gorm:"not null;default:1" json:"version"`
+	// DeletedAt is set for soft-deleted subscriptions until they are restored or purged.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (s *Subscription) Validate() error {
+	if strings.TrimSpace(s.ServiceName) == "" {
+		return fmt.Errorf("%w: service_name is required", ErrInvalidSubscription)
+	}
+	if s.Price <= 0 {
+		return fmt.Errorf("%w: price should be > 0", ErrInvalidSubscription)
+	}
+	if s.UserID == uuid.Nil {
+		return fmt.Errorf("%w: user_id is required", ErrInvalidSubscription)
+	}
+	if s.EndDate != nil && s.EndDate.IsBefore(s.StartDate) {
+		return fmt.Errorf("%w: end date should not be before start date", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// SubscriptionFilter narrows listed and exported subscriptions, nil fields are not applied.
+// ActiveFrom and ActiveTo keep subscriptions active at some point of the period.
+type SubscriptionFilter struct {
+	UserID      *uuid.UUID
+	ServiceName *string
+	ActiveFrom  *MonthYear
+	ActiveTo    *MonthYear
+	// IncludeDeleted also returns soft-deleted subscriptions.
+	IncludeDeleted bool
+}
+
+type CostGroup string
+
+const (
+	CostGroupServiceName CostGroup = "service_name"
+	CostGroupUserID      CostGroup = "user_id"
+	CostGroupMonth       CostGroup = "month"
+)
+
+func (g CostGroup) Valid() bool {
+	return g == CostGroupServiceName || g == CostGroupUserID || g == CostGroupMonth
+}
+
+type CostBreakdownLine struct {
+	Group         string `json:"group"`
+	Subscriptions int    `json:"subscriptions"`
+	ActiveMonths  int    `json:"active_months"`
+	Total         int    `json:"total"`
+}
+
+// AnyVersion skips the optimistic concurrency check (If-Match: *).
+const AnyVersion = 0
+
+// SubscriptionPatch is a parsed JSON Merge Patch (RFC 7396).
+// Nil fields are left untouched, ClearEndDate resets end_date to open-ended.
+type SubscriptionPatch struct {
+	ServiceName  *string
+	Price        *int
+	UserID       *uuid.UUID
+	StartDate    *MonthYear
+	EndDate      *MonthYear
+	ClearEndDate bool
+}
+
+func (p SubscriptionPatch) Apply(s *Subscription) {
+	if p.ServiceName != nil {
+		s.ServiceName = *p.ServiceName
+	}
+	if p.Price != nil {
+		s.Price = *p.Price
+	}
+	if p.UserID != nil {
+		s.UserID = *p.UserID
+	}
+	if p.StartDate != nil {
+		s.StartDate = *p.StartDate
+	}
+	if p.ClearEndDate {
+		s.EndDate = nil
+	} else if p.EndDate != nil {
+		endDate := *p.EndDate
+		s.EndDate = &endDate
+	}
 }
 
 type SubscriptionInteractor interface {
-	AddSubscription(ctx context.Context, serviceName string, price int, userID uuid.UUID, startDate MonthYear, endDate *MonthYear) (uuid.UUID, error)
+	// AddSubscription returns the stored subscription with its ID, tenant and version.
+	AddSubscription(ctx context.Context, serviceName string, price int, userID uuid.UUID, startDate MonthYear, endDate *MonthYear) (*Subscription, error)
 	Subscription(ctx context.Context, subscriptionID uuid.UUID) (*Subscription, error)
-	DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID) error
-	UpdateSubscription(ctx context.Context, subscriptionID uuid.UUID, serviceName string, price int, userID uuid.UUID, startDate MonthYear, endDate *MonthYear) error
-	ListSubscription(ctx context.Context, offset, limit int) ([]*Subscription, int64, error)
-	TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate MonthYear) (int, error)
+	DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) error
+	UpdateSubscription(ctx context.Context, subscriptionID uuid.UUID, serviceName string, price int, userID uuid.UUID, startDate MonthYear, endDate *MonthYear, version int) (*Subscription, error)
+	PatchSubscription(ctx context.Context, subscriptionID uuid.UUID, patch SubscriptionPatch, version int) (*Subscription, error)
+	RestoreSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*Subscription, error)
+	ListSubscription(ctx context.Context, offset, limit int, includeDeleted bool) ([]*Subscription, int64, error)
+	TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate MonthYear, includeDeleted bool) (int, error)
+	CostBreakdown(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate MonthYear, groupBy CostGroup, includeDeleted bool) ([]CostBreakdownLine, error)
+	ExportSubscriptions(ctx context.Context, filter SubscriptionFilter, fn func(*Subscription) error) error
+	BulkCreate(ctx context.Context, subscriptions []*Subscription, mode BulkMode) ([]BulkResult, error)
+	BulkUpdate(ctx context.Context, subscriptions []*Subscription, mode BulkMode) ([]BulkResult, error)
+	BulkDelete(ctx context.Context, refs []SubscriptionRef, mode BulkMode) ([]BulkResult, error)
+	History(ctx context.Context, subscriptionID uuid.UUID, offset, limit int) ([]AuditEntry, int64, error)
 }
 
 type SubscriptionRepository interface {
 	SaveSubscription(ctx context.Context, subscription *Subscription) (uuid.UUID, error)
-	Subscription(ctx context.Context, subscriptionID uuid.UUID) (*Subscription, error)
-	DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID) error
+	SaveSubscriptions(ctx context.Context, subscriptions []*Subscription) error
+	Subscription(ctx context.Context, subscriptionID uuid.UUID, includeDeleted bool) (*Subscription, error)
+	// DeleteSubscription soft-deletes a subscription and returns the deleted row.
+	DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*Subscription, error)
+	RestoreSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*Subscription, error)
+	// PurgeDeleted removes up to limit subscriptions deleted before the given time and returns them.
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) ([]*Subscription, error)
 	UpdateSubscription(ctx context.Context, subscription *Subscription) error
-	ListSubscription(ctx context.Context, offset, limit int) ([]*Subscription, error)
-	TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate MonthYear) ([]Subscription, error)
-	Count(ctx context.Context) (int64, error)
+	ListSubscription(ctx context.Context, filter SubscriptionFilter, offset, limit int) ([]*Subscription, error)
+	TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate MonthYear, includeDeleted bool) ([]Subscription, error)
+	// StreamSubscriptions calls fn for every matching row without loading the whole result set.
+	StreamSubscriptions(ctx context.Context, filter SubscriptionFilter, fn func(*Subscription) error) error
+	Count(ctx context.Context, filter SubscriptionFilter) (int64, error)
 }
 
 type AddSubcriptionRequest struct {
@@ -50,3 +161,13 @@ type UpdateSubcriptionRequest struct {
 	StartDateRaw      string `json:"start_date" binding:"required" example:"07-2025"`
 	EndDateRaw        string `json:"end_date" example:"07-2026"`
 }
+
+// PatchSubscriptionRequest documents the merge patch body, every field is optional
+// and "end_date": null makes the subscription open-ended.
+type PatchSubscriptionRequest struct {
+	ServiceName  *string  `json:"service_name,omitempty" example:"Yandex Plus"`
+	Price        *float64 `json:"price,omitempty" example:"400"`
+	UserIDRaw    *string  `json:"user_id,omitempty" example:"a19df875-4040-4fc3-84ad-003d013fcd89"`
+	StartDateRaw *string  `json:"start_date,omitempty" example:"07-2025"`
+	EndDateRaw   *string  `json:"end_date" example:"07-2026"`
+}
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// DefaultTenant owns the data of single-tenant deployments and of callers whose token
+// names no organization.
+var DefaultTenant = uuid.Nil
+
+type tenantKey struct{}
+
+// WithTenant limits every repository call made with ctx to the data of tenantID.
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the organization of the request. Calls without a tenant come
+// from trusted code such as CLI commands and background jobs and see every tenant.
+func TenantFromContext(ctx context.Context) (uuid.UUID, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(uuid.UUID)
+	return tenantID, ok
+}
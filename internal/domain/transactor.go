@@ -0,0 +1,9 @@
+package domain
+
+import "context"
+
+// Transactor runs fn in a database transaction. Repositories called with the ctx passed
+// to fn take part in that transaction, nested calls join the outer one.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookEndpoint receives events of EventTypes (all events when empty) for the
+// subscriptions of UserID (all users when nil) in its tenant. Secret is only shown on
+// registration.
+type WebhookEndpoint struct {
+	ID         uuid.UUID   `json:"id"`
+	TenantID   uuid.UUID   `json:"tenant_id"`
+	URL        string      `json:"url"`
+	Secret     string      `json:"secret,omitempty"`
+	EventTypes []EventType `json:"event_types"`
+	UserID     *uuid.UUID  `json:"user_id,omitempty"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      EventType       `json:"event_type"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *WebhookEndpoint) error
+	Endpoint(ctx context.Context, endpointID uuid.UUID) (*WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context) ([]*WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, endpointID uuid.UUID) error
+	// Enqueue schedules a delivery of payload to every active endpoint subscribed to event.
+	// An event already enqueued for an endpoint is skipped.
+	Enqueue(ctx context.Context, event Event, payload []byte) (int64, error)
+	// ClaimDeliveries returns due pending deliveries and postpones them by lease, so
+	// concurrent workers do not send the same delivery twice.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	ListDeliveries(ctx context.Context, endpointID uuid.UUID, offset, limit int) ([]*WebhookDelivery, int64, error)
+}
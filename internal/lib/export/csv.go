@@ -0,0 +1,46 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type CSVWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteRow(cells ...interface{}) error {
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case int, int64:
+			c.record = append(c.record, fmt.Sprint(v))
+		default:
+			c.record = append(c.record, textCell(v))
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// textCell renders a non-numeric cell. Text starting like a formula is prefixed with a
+// quote, so that spreadsheets opening the file show it instead of evaluating it (CSV
+// injection). XLSX needs no such prefix, its text cells are never evaluated.
+func textCell(cell interface{}) string {
+	text := fmt.Sprint(cell)
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,102 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Event is an all-day VEVENT. RRule is written as is, e.g. "FREQ=MONTHLY;UNTIL=20260701".
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Date        time.Time
+	RRule       string
+	Sequence    int
+	Stamp       time.Time
+}
+
+// Writer streams an RFC 5545 VCALENDAR.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func NewWriter(w io.Writer, prodID, name string) *Writer {
+	cw := &Writer{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + Escape(name))
+	return cw
+}
+
+func (cw *Writer) WriteEvent(e Event) error {
+	cw.line("BEGIN:VEVENT")
+	cw.line("UID:" + e.UID)
+	cw.line("DTSTAMP:" + e.Stamp.UTC().Format(dateTimeFormat))
+	cw.line("DTSTART;VALUE=DATE:" + e.Date.Format(dateFormat))
+	cw.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format(dateFormat))
+	if e.RRule != "" {
+		cw.line("RRULE:" + e.RRule)
+	}
+	cw.line("SUMMARY:" + Escape(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION:" + Escape(e.Description))
+	}
+	if e.Sequence > 0 {
+		cw.line("SEQUENCE:" + strconv.Itoa(e.Sequence))
+	}
+	cw.line("TRANSP:TRANSPARENT")
+	cw.line("END:VEVENT")
+	return cw.err
+}
+
+func (cw *Writer) Close() error {
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+func FormatDate(t time.Time) string {
+	return t.Format(dateFormat)
+}
+
+// Escape escapes a TEXT value (RFC 5545, 3.3.11).
+func Escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// line writes a content line folded at 75 octets without splitting UTF-8 sequences.
+func (cw *Writer) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		cw.w.WriteString(s[:cut])
+		cw.w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	cw.w.WriteString(s)
+	_, cw.err = cw.w.WriteString("\r\n")
+}
@@ -0,0 +1,196 @@
+// Package jwt verifies compact JWS tokens signed with HS256 or RS256.
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMalformed        = errors.New("malformed token")
+	ErrUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	ErrUnknownKey       = errors.New("unknown signing key")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("token is expired")
+	ErrMissingExp       = errors.New("token has no expiration time")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("invalid issuer")
+	ErrInvalidAudience  = errors.New("invalid audience")
+)
+
+// Audience is the aud claim, which is either a string or an array of strings.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a Audience) Contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims holds the registered claims, the whole payload is kept in Raw for custom ones.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Raw       map[string]json.RawMessage
+}
+
+// String returns a custom string claim, empty when it is missing or not a string.
+func (c *Claims) String(name string) string {
+	var value string
+	_ = json.Unmarshal(c.Raw[name], &value)
+	return value
+}
+
+// Strings returns a custom claim holding a string or an array of strings.
+func (c *Claims) Strings(name string) []string {
+	var values Audience
+	_ = json.Unmarshal(c.Raw[name], &values)
+	return values
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type Options struct {
+	// HMACSecret verifies HS256 tokens, HS256 is rejected when it is empty.
+	HMACSecret []byte
+	// RSAKeys verify RS256 tokens by kid, the "" key is used for tokens without one.
+	RSAKeys  map[string]*rsa.PublicKey
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+type Verifier struct {
+	opts Options
+	now  func() time.Time
+}
+
+func NewVerifier(opts Options) *Verifier {
+	return &Verifier{opts: opts, now: time.Now}
+}
+
+// Verify checks the signature and the time, issuer and audience claims of token, exp
+// is required.
+// The algorithm is taken from the header only to pick the key of the matching type,
+// so an RSA public key can never be used as an HMAC secret.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	signed := parts[0] + "." + parts[1]
+	if err := v.verifySignature(h, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := decodeSegment(parts[1], &claims.Raw); err != nil {
+		return nil, err
+	}
+	if err := v.validate(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (v *Verifier) verifySignature(h header, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch h.Alg {
+	case "HS256":
+		if len(v.opts.HMACSecret) == 0 {
+			return ErrUnsupportedAlg
+		}
+		mac := hmac.New(sha256.New, v.opts.HMACSecret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "RS256":
+		key, ok := v.opts.RSAKeys[h.Kid]
+		if !ok {
+			return ErrUnknownKey
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlg, h.Alg)
+	}
+}
+
+func (v *Verifier) validate(claims *Claims) error {
+	now := v.now()
+	// A token without exp would stay valid forever once leaked.
+	if claims.ExpiresAt == nil {
+		return ErrMissingExp
+	}
+	if !now.Before(time.Unix(*claims.ExpiresAt, 0).Add(v.opts.Leeway)) {
+		return ErrExpired
+	}
+	if claims.NotBefore != nil && now.Add(v.opts.Leeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return ErrNotYetValid
+	}
+	if v.opts.Issuer != "" && claims.Issuer != v.opts.Issuer {
+		return ErrInvalidIssuer
+	}
+	if v.opts.Audience != "" && !claims.Audience.Contains(v.opts.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("test-secret")
+
+// signHS256 returns a compact HS256 token with claims as its payload.
+func signHS256(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyExpiration(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		wantErr error
+	}{
+		{name: "valid", claims: map[string]any{"sub": "user", "exp": now.Add(time.Hour).Unix()}},
+		{name: "within leeway", claims: map[string]any{"sub": "user", "exp": now.Add(-10 * time.Second).Unix()}},
+		{name: "expired", claims: map[string]any{"sub": "user", "exp": now.Add(-time.Minute).Unix()}, wantErr: ErrExpired},
+		{name: "no exp", claims: map[string]any{"sub": "user"}, wantErr: ErrMissingExp},
+		{name: "null exp", claims: map[string]any{"sub": "user", "exp": nil}, wantErr: ErrMissingExp},
+		{
+			name:    "not yet valid",
+			claims:  map[string]any{"sub": "user", "exp": now.Add(time.Hour).Unix(), "nbf": now.Add(time.Minute).Unix()},
+			wantErr: ErrNotYetValid,
+		},
+	}
+	verifier := NewVerifier(Options{HMACSecret: testSecret, Leeway: 30 * time.Second})
+	verifier.now = func() time.Time { return now }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(signHS256(t, tt.claims))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims.Subject != "user" {
+				t.Errorf("Verify() subject = %q, want %q", claims.Subject, "user")
+			}
+		})
+	}
+}
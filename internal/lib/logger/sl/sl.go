@@ -1,6 +1,7 @@
 package sl
 
 import (
+	"context"
 	"log/slog"
 )
 
@@ -10,3 +11,19 @@ func Err(err error) slog.Attr {
 		Value: slog.StringValue(err.Error()),
 	}
 }
+
+type loggerKey struct{}
+
+// ContextWithLogger stores a logger scoped to one request, lines written through it
+// carry the request ID.
+func ContextWithLogger(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, log)
+}
+
+// LoggerFromContext returns the request logger, or fallback outside of a request.
+func LoggerFromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
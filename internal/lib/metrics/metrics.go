@@ -0,0 +1,282 @@
+// Package metrics exposes counters, gauges and histograms in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets suit request and query durations in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry collects metrics and serves them to Prometheus.
+type Registry struct {
+	mu        sync.Mutex
+	metrics   []metric
+	names     map[string]bool
+	onCollect []func(ctx context.Context)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// OnCollect runs fn before every scrape, e.g. to refresh gauges that are expensive to keep current.
+func (r *Registry) OnCollect(fn func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onCollect = append(r.onCollect, fn)
+}
+
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec[float64](name, help, "counter", labels)}
+	r.register(name, c)
+	return c
+}
+
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: newVec[float64](name, help, "gauge", labels)}
+	r.register(name, g)
+	return g
+}
+
+// Histogram counts observations in cumulative buckets with the given upper bounds.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{vec: newVec[histogramValue](name, help, "histogram", labels), buckets: buckets}
+	r.register(name, h)
+	return h
+}
+
+// GaugeFunc reports the value of fn at every scrape.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// CounterFunc reports the value of fn at every scrape, fn should never decrease.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	hooks := append([]func(context.Context){}, r.onCollect...)
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+	for _, hook := range hooks {
+		hook(req.Context())
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	bw.Flush()
+}
+
+// vec keeps one series per combination of label values.
+type vec[T any] struct {
+	name, help, kind string
+	labels           []string
+	mu               sync.Mutex
+	series           map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	value  T
+}
+
+func newVec[T any](name, help, kind string, labels []string) vec[T] {
+	return vec[T]{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*series[T])}
+}
+
+// with runs fn on the series of values under the lock of the vec.
+func (v *vec[T]) with(values []string, fn func(value *T)) {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	fn(&s.value)
+}
+
+// each calls fn for every series in a stable order.
+func (v *vec[T]) each(fn func(labels string, value T)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := v.series[key]
+		fn(labelPairs(v.labels, s.values), s.value)
+	}
+}
+
+func (v *vec[T]) header(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, v.kind)
+}
+
+type Counter struct {
+	vec vec[float64]
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.vec.name + " cannot decrease")
+	}
+	c.vec.with(values, func(value *float64) { *value += delta })
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.vec.header(w)
+	c.vec.each(func(labels string, value float64) {
+		writeSample(w, c.vec.name, labels, value)
+	})
+}
+
+type Gauge struct {
+	vec vec[float64]
+}
+
+func (g *Gauge) Set(value float64, values ...string) {
+	g.vec.with(values, func(v *float64) { *v = value })
+}
+
+// Reset drops every series, e.g. before setting the values of the current scrape.
+func (g *Gauge) Reset() {
+	g.vec.mu.Lock()
+	defer g.vec.mu.Unlock()
+	clear(g.vec.series)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.vec.header(w)
+	g.vec.each(func(labels string, value float64) {
+		writeSample(w, g.vec.name, labels, value)
+	})
+}
+
+type histogramValue struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+type Histogram struct {
+	vec     vec[histogramValue]
+	buckets []float64
+}
+
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.vec.with(values, func(v *histogramValue) {
+		if v.counts == nil {
+			v.counts = make([]uint64, len(h.buckets))
+		}
+		for i, bound := range h.buckets {
+			if value <= bound {
+				v.counts[i]++
+			}
+		}
+		v.count++
+		v.sum += value
+	})
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.vec.header(w)
+	h.vec.each(func(labels string, value histogramValue) {
+		for i, bound := range h.buckets {
+			writeSample(w, h.vec.name+"_bucket", withLabel(labels, "le", formatFloat(bound)), float64(value.counts[i]))
+		}
+		writeSample(w, h.vec.name+"_bucket", withLabel(labels, "le", "+Inf"), float64(value.count))
+		writeSample(w, h.vec.name+"_sum", labels, value.sum)
+		writeSample(w, h.vec.name+"_count", labels, float64(value.count))
+	})
+}
+
+type funcMetric struct {
+	name, help, kind string
+	fn               func() float64
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, m.name, m.help, m.kind)
+	writeSample(w, m.name, "", m.fn())
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelPairs(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return pair
+	}
+	return labels + "," + pair
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrNotWhole = errors.New("amount should be a whole number")
+
+var spaces = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "")
+
+// ParseMinor parses an amount written with either decimal separator, such as "-1 234,56",
+// "1,234.50", "1.234" or "399", into minor units (kopecks, cents).
+//
+// When both separators are present the last one is the decimal separator. A separator
+// repeated in the number ("1,234,567"), or used once with exactly three digits after it
+// and a non-zero integer part ("1,234"), separates thousands. Otherwise it is decimal.
+func ParseMinor(s string) (int64, error) {
+	raw := s
+	s = spaces.Replace(strings.TrimSpace(s))
+	dot, comma := strings.Count(s, "."), strings.Count(s, ",")
+	switch {
+	case dot > 0 && comma > 0:
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case dot > 1 || comma > 1:
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	case dot == 1 || comma == 1:
+		i := strings.IndexAny(s, ".,")
+		integer := strings.TrimLeft(s[:i], "+-")
+		if len(s)-i-1 == 3 && integer != "" && strings.Trim(integer, "0") != "" {
+			s = s[:i] + s[i+1:]
+		}
+	}
+	s = strings.ReplaceAll(s, ",", ".")
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("incorrect amount %q", raw)
+	}
+	return int64(math.Round(value * 100)), nil
+}
+
+// ParseWhole parses an amount like ParseMinor and requires it to have no fractional part.
+func ParseWhole(s string) (int64, error) {
+	minor, err := ParseMinor(s)
+	if err != nil {
+		return 0, err
+	}
+	if minor%100 != 0 {
+		return 0, ErrNotWhole
+	}
+	return minor / 100, nil
+}
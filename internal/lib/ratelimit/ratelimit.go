@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("rate limit should allow at least one request")
+
+// Limit is a token bucket: it holds up to Burst requests and refills with Rate
+// requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns a limit of n requests per period with the given burst, which is n
+// when not positive.
+func Every(n int, period time.Duration, burst int) Limit {
+	if burst <= 0 {
+		burst = n
+	}
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: burst}
+}
+
+func (l Limit) Validate() error {
+	if l.Rate <= 0 || l.Burst < 1 || math.IsInf(l.Rate, 0) {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+// Window is how long an empty bucket takes to refill.
+func (l Limit) Window() time.Duration {
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Result is the state of a bucket after taking a request from it.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when it already is.
+	RetryAfter time.Duration
+}
+
+// Store keeps buckets by key, e.g. a client and a route.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// NewResult describes a bucket left with tokens after a request was allowed or not.
+func NewResult(limit Limit, tokens float64, allowed bool) Result {
+	result := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if tokens < 1 {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return result
+}
+
+// take refills a bucket holding tokens for elapsed and takes a request from it.
+func take(limit Limit, tokens float64, elapsed time.Duration) (float64, bool) {
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+	if tokens < 1 {
+		return tokens, false
+	}
+	return tokens - 1, true
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// Policy is the limit of every route, Routes override Default by "METHOD /route/template".
+type Policy struct {
+	Default Limit
+	Routes  map[string]Limit
+}
+
+func (p Policy) For(method, route string) Limit {
+	if limit, ok := p.Routes[method+" "+route]; ok {
+		return limit
+	}
+	return p.Default
+}
@@ -0,0 +1,267 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Exporter sends finished spans to a tracing backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// BatchProcessor queues finished spans and exports them in batches, spans that do not
+// fit in the queue are dropped rather than slowing requests down.
+type BatchProcessor struct {
+	log       *slog.Logger
+	exporter  Exporter
+	interval  time.Duration
+	batchSize int
+	queue     chan *Span
+	dropped   atomic.Int64
+}
+
+func NewBatchProcessor(log *slog.Logger, exporter Exporter, interval time.Duration, batchSize int) *BatchProcessor {
+	return &BatchProcessor{
+		log:       log,
+		exporter:  exporter,
+		interval:  interval,
+		batchSize: batchSize,
+		queue:     make(chan *Span, 4*batchSize),
+	}
+}
+
+func (p *BatchProcessor) onEnd(span *Span) {
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// Run exports queued spans until ctx is done and then exports what is left.
+func (p *BatchProcessor) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, p.batchSize)
+	flush := func(ctx context.Context) {
+		if dropped := p.dropped.Swap(0); dropped > 0 {
+			p.log.Warn("trace spans dropped, queue is full", slog.Int64("dropped", dropped))
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			p.log.Error("failed to export trace spans", slog.Int("spans", len(batch)), slog.String("error", err.Error()))
+		}
+		batch = make([]*Span, 0, p.batchSize)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) == p.batchSize {
+						flush(shutdown)
+					}
+				default:
+					flush(shutdown)
+					return
+				}
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) == p.batchSize {
+				flush(ctx)
+			}
+		}
+	}
+}
+
+// StdoutExporter writes spans as JSON lines, for local runs.
+type StdoutExporter struct {
+	w io.Writer
+}
+
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{w: w}
+}
+
+func (e *StdoutExporter) Export(_ context.Context, spans []*Span) error {
+	enc := json.NewEncoder(e.w)
+	for _, span := range spans {
+		line := map[string]any{
+			"trace_id":    span.Context.TraceID.String(),
+			"span_id":     span.Context.SpanID.String(),
+			"name":        span.Name,
+			"start":       span.Start,
+			"duration_ms": float64(span.EndTime.Sub(span.Start).Microseconds()) / 1000,
+		}
+		if span.Parent.IsValid() {
+			line["parent_id"] = span.Parent.String()
+		}
+		if len(span.Attributes) > 0 {
+			attrs := make(map[string]any, len(span.Attributes))
+			for _, attr := range span.Attributes {
+				attrs[attr.Key] = attr.Value
+			}
+			line["attributes"] = attrs
+		}
+		if span.Err != nil {
+			line["error"] = span.Err.Error()
+		}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector in the JSON encoding, e.g. to
+// Jaeger or the OpenTelemetry Collector on port 4318.
+type OTLPExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter sends spans to endpoint, e.g. "http://localhost:4318"; the
+// /v1/traces path is added unless endpoint already has it.
+func NewOTLPExporter(endpoint, serviceName string, timeout time.Duration) *OTLPExporter {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	return &OTLPExporter{url: url, serviceName: serviceName, client: &http.Client{Timeout: timeout}}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+// otlpStatusError is STATUS_CODE_ERROR.
+const otlpStatusError = 2
+
+func (e *OTLPExporter) request(spans []*Span) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if span.Parent.IsValid() {
+			s.ParentSpanID = span.Parent.String()
+		}
+		if span.Err != nil {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Err.Error()}
+		}
+		converted = append(converted, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes([]Attribute{String("service.name", e.serviceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: e.serviceName}, Spans: converted}},
+	}}}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpAttribute {
+	converted := make([]otlpAttribute, 0, len(attrs))
+	for _, attr := range attrs {
+		var value map[string]any
+		switch v := attr.Value.(type) {
+		case string:
+			value = map[string]any{"stringValue": v}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		case float64:
+			value = map[string]any{"doubleValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		converted = append(converted, otlpAttribute{Key: attr.Key, Value: value})
+	}
+	return converted
+}
@@ -0,0 +1,200 @@
+// Package tracing records spans, propagates them with W3C trace context and exports
+// them in the OpenTelemetry protocol.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext identifies a span across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+type SpanKind int
+
+// Span kinds as numbered by OTLP.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func Int(key string, value int) Attribute {
+	return Attribute{Key: key, Value: int64(value)}
+}
+
+// Span is an operation of a trace. Spans that are not sampled are only kept to
+// propagate their context and are never exported.
+type Span struct {
+	tracer     *Tracer
+	Name       string
+	Kind       SpanKind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	EndTime    time.Time
+	Attributes []Attribute
+	Err        error
+	ended      atomic.Bool
+	mu         sync.Mutex
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.Context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.Attributes = append(s.Attributes, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed with err, nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil || !s.Context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.Err = err
+	s.mu.Unlock()
+}
+
+// End finishes the span and hands it to the exporter, later calls do nothing.
+func (s *Span) End() {
+	if s == nil || !s.ended.CompareAndSwap(false, true) || !s.Context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	s.tracer.processor.onEnd(s)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan makes span the parent of spans started with the returned context.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the current span, nil when there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+type remoteKey struct{}
+
+// ContextWithRemoteParent makes sc, received from another service, the parent of spans
+// started with the returned context.
+func ContextWithRemoteParent(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the context of the current span or of the remote parent.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.Context, true
+	}
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+type StartOption func(*Span)
+
+func WithKind(kind SpanKind) StartOption {
+	return func(s *Span) { s.Kind = kind }
+}
+
+func WithAttributes(attrs ...Attribute) StartOption {
+	return func(s *Span) { s.Attributes = append(s.Attributes, attrs...) }
+}
+
+// Tracer starts spans and passes the sampled ones to its processor.
+type Tracer struct {
+	processor   *BatchProcessor
+	sampleRatio float64
+}
+
+// NewTracer samples sampleRatio of new traces, spans with a parent follow its decision.
+func NewTracer(processor *BatchProcessor, sampleRatio float64) *Tracer {
+	return &Tracer{processor: processor, sampleRatio: sampleRatio}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	span := &Span{tracer: t, Name: name, Kind: KindInternal, Start: time.Now()}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.Context.TraceID = parent.TraceID
+		span.Context.Sampled = parent.Sampled
+		span.Parent = parent.SpanID
+	} else {
+		crand.Read(span.Context.TraceID[:])
+		span.Context.Sampled = t.processor != nil && rand.Float64() < t.sampleRatio
+	}
+	binary.BigEndian.PutUint64(span.Context.SpanID[:], rand.Uint64()|1)
+	if t.processor == nil {
+		span.Context.Sampled = false
+	}
+	for _, opt := range opts {
+		opt(span)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+var global atomic.Pointer[Tracer]
+
+func init() {
+	global.Store(&Tracer{})
+}
+
+// SetTracer replaces the tracer used by Start, which records nothing by default.
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+// Start starts a span with the global tracer. The span must be ended by the caller.
+func Start(ctx context.Context, name string, opts ...StartOption) (context.Context, *Span) {
+	return global.Load().Start(ctx, name, opts...)
+}
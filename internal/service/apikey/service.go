@@ -0,0 +1,181 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const (
+	keyPrefix = "sak_"
+	// touchEvery limits last-used tracking to one write per key and interval.
+	touchEvery = time.Minute
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrInvalidParams = errors.New("invalid api key parameters")
+)
+
+type Service struct {
+	log           *slog.Logger
+	repo          domain.APIKeyRepository
+	tx            domain.Transactor
+	rotationGrace time.Duration
+}
+
+// NewService creates the key manager. A rotated key keeps working for rotationGrace,
+// so its clients can switch to the new key without downtime.
+func NewService(log *slog.Logger, repo domain.APIKeyRepository, tx domain.Transactor, rotationGrace time.Duration) *Service {
+	return &Service{log: log, repo: repo, tx: tx, rotationGrace: rotationGrace}
+}
+
+// Issue creates a key and returns it with its secret, which is not stored and cannot
+// be shown again.
+func (s *Service) Issue(ctx context.Context, name string, scopes []domain.Scope, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	const op = "service.apikey.issue"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("name", name),
+	)
+	if strings.TrimSpace(name) == "" {
+		return nil, "", fmt.Errorf("%s: %w: name is required", op, ErrInvalidParams)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%s: %w: at least one scope is required", op, ErrInvalidParams)
+	}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", fmt.Errorf("%s: %w: unknown scope %q", op, ErrInvalidParams, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%s: %w: expires_at should be in the future", op, ErrInvalidParams)
+	}
+	key := &domain.APIKey{Name: name, Scopes: scopes, ExpiresAt: expiresAt}
+	secret, err := s.create(ctx, key)
+	if err != nil {
+		log.Error("failed to issue api key", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("api key issued", slog.String("key_id", key.ID.String()), slog.String("prefix", key.Prefix))
+	return key, secret, nil
+}
+
+// Rotate issues a key with the name and scopes of keyID and lets the old key expire
+// after the rotation grace period.
+func (s *Service) Rotate(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, string, error) {
+	const op = "service.apikey.rotate"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("key_id", keyID.String()),
+	)
+	var rotated *domain.APIKey
+	var secret string
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		old, err := s.repo.APIKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !old.Active(now) {
+			return fmt.Errorf("%w: key is revoked or expired", ErrInvalidParams)
+		}
+		rotated = &domain.APIKey{TenantID: old.TenantID, Name: old.Name, Scopes: old.Scopes, ExpiresAt: old.ExpiresAt}
+		if secret, err = s.create(ctx, rotated); err != nil {
+			return err
+		}
+		return s.repo.ExpireAPIKey(ctx, keyID, now.Add(s.rotationGrace))
+	})
+	if err != nil {
+		log.Error("failed to rotate api key", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("api key rotated", slog.String("new_key_id", rotated.ID.String()))
+	return rotated, secret, nil
+}
+
+func (s *Service) Revoke(ctx context.Context, keyID uuid.UUID) error {
+	const op = "service.apikey.revoke"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("key_id", keyID.String()),
+	)
+	if err := s.repo.RevokeAPIKey(ctx, keyID, time.Now()); err != nil {
+		log.Error("failed to revoke api key", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("api key revoked")
+	return nil
+}
+
+func (s *Service) Keys(ctx context.Context) ([]*domain.APIKey, error) {
+	const op = "service.apikey.list"
+	keys, err := s.repo.ListAPIKeys(ctx)
+	if err != nil {
+		sl.LoggerFromContext(ctx, s.log).Error("failed to list api keys", slog.String("op", op), sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+// Authenticate returns the active key matching secret and records its use.
+func (s *Service) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	const op = "service.apikey.authenticate"
+	if !strings.HasPrefix(secret, keyPrefix) {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidKey)
+	}
+	key, err := s.repo.APIKeyByHash(ctx, hashKey(secret))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s: %w", op, ErrInvalidKey)
+	}
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, fmt.Errorf("%s: %w: key is revoked or expired", op, ErrInvalidKey)
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchEvery {
+		if err := s.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			sl.LoggerFromContext(ctx, s.log).Warn("failed to record api key use", slog.String("op", op), sl.Err(err))
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// create generates the secret of key and stores key with its hash.
+func (s *Service) create(ctx context.Context, key *domain.APIKey) (string, error) {
+	public := make([]byte, 4)
+	private := make([]byte, 32)
+	if _, err := rand.Read(public); err != nil {
+		return "", err
+	}
+	if _, err := rand.Read(private); err != nil {
+		return "", err
+	}
+	key.Prefix = keyPrefix + hex.EncodeToString(public)
+	secret := key.Prefix + "_" + base64.RawURLEncoding.EncodeToString(private)
+	if err := s.repo.CreateAPIKey(ctx, key, hashKey(secret)); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// hashKey needs no salt or stretching: keys are 256 random bits, not passwords.
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/ical"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const (
+	prodID    = "-//subscription-aggregator//calendar//EN"
+	uidDomain = "subscription-aggregator"
+)
+
+type FeedGenerator struct {
+	log                 *slog.Logger
+	subscriptionService domain.SubscriptionInteractor
+}
+
+func NewFeedGenerator(log *slog.Logger, subscriptionService domain.SubscriptionInteractor) *FeedGenerator {
+	return &FeedGenerator{log: log, subscriptionService: subscriptionService}
+}
+
+// WriteUserFeed writes an .ics calendar with the charges and expirations of every
+// subscription of the user.
+func (g *FeedGenerator) WriteUserFeed(ctx context.Context, w io.Writer, userID uuid.UUID) error {
+	const op = "service.calendar.userFeed"
+	log := sl.LoggerFromContext(ctx, g.log).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+	log.Info("generating calendar feed")
+	now := time.Now()
+	cal := ical.NewWriter(w, prodID, "Subscriptions")
+	filter := domain.SubscriptionFilter{UserID: &userID}
+	err := g.subscriptionService.ExportSubscriptions(ctx, filter, func(subscription *domain.Subscription) error {
+		for _, event := range Events(subscription, now) {
+			if err := cal.WriteEvent(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = cal.Close()
+	}
+	if err != nil {
+		log.Error("failed to generate calendar feed", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Events converts a subscription into calendar events. Only the month of a charge is
+// known, so charges fall on the first day of every active month, recurring until
+// EndDate or indefinitely for open-ended subscriptions. A subscription with EndDate
+// also gets an expiration event on the last day of that month.
+// Subscriptions don't record trial periods, so there are no trial end events.
+func Events(subscription *domain.Subscription, now time.Time) []ical.Event {
+	charge := ical.Event{
+		UID:         fmt.Sprintf("%s-charge@%s", subscription.ID, uidDomain),
+		Summary:     fmt.Sprintf("%s: %d", subscription.ServiceName, subscription.Price),
+		Description: fmt.Sprintf("Monthly charge for %s, subscription %s", subscription.ServiceName, subscription.ID),
+		Date:        subscription.StartDate.ToTime(),
+		RRule:       "FREQ=MONTHLY",
+		Sequence:    subscription.Version,
+		Stamp:       now,
+	}
+	if subscription.EndDate == nil {
+		return []ical.Event{charge}
+	}
+
+	charge.RRule += ";UNTIL=" + ical.FormatDate(subscription.EndDate.ToTime())
+	expiration := ical.Event{
+		UID:         fmt.Sprintf("%s-end@%s", subscription.ID, uidDomain),
+		Summary:     fmt.Sprintf("%s subscription ends", subscription.ServiceName),
+		Description: fmt.Sprintf("Subscription %s expires at the end of %s", subscription.ID, subscription.EndDate),
+		Date:        subscription.EndDate.ToTime().AddDate(0, 1, -1),
+		Sequence:    subscription.Version,
+		Stamp:       now,
+	}
+	return []ical.Event{charge, expiration}
+}
@@ -0,0 +1,244 @@
+package csvimport
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/money"
+)
+
+const MaxRows = 10000
+
+var (
+	ErrInvalidCSV   = errors.New("invalid csv")
+	ErrInvalidRows  = errors.New("csv contains invalid rows")
+	isoMonthPattern = regexp.MustCompile(`^(\d{4})-(\d{2})(-\d{2})?$`)
+)
+
+type Options struct {
+	Mapping   Mapping
+	Delimiter rune
+	DryRun    bool
+}
+
+type RowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+type Report struct {
+	DryRun   bool        `json:"dry_run"`
+	Rows     int         `json:"rows"`
+	Valid    int         `json:"valid"`
+	Imported int         `json:"imported"`
+	IDs      []uuid.UUID `json:"ids,omitempty"`
+	Errors   []RowError  `json:"errors,omitempty"`
+}
+
+type Importer struct {
+	log                 *slog.Logger
+	subscriptionService domain.SubscriptionInteractor
+}
+
+func NewImporter(log *slog.Logger, subscriptionService domain.SubscriptionInteractor) *Importer {
+	return &Importer{log: log, subscriptionService: subscriptionService}
+}
+
+// Import validates every row and checks that the caller may create subscriptions of
+// its user, then, unless DryRun is set, creates all subscriptions in one transaction.
+// Nothing is created when at least one row is invalid or denied; denied rows fail with
+// domain.ErrForbidden, the rest with ErrInvalidRows, and either way the report lists
+// the rows.
+func (im *Importer) Import(ctx context.Context, r io.Reader, opts Options) (*Report, error) {
+	const op = "service.csvimport.import"
+	log := sl.LoggerFromContext(ctx, im.log).With(
+		slog.String("op", op),
+		slog.Bool("dry_run", opts.DryRun),
+	)
+	log.Info("importing subscriptions from csv")
+	if opts.Mapping == nil {
+		opts.Mapping = DefaultMapping()
+	}
+
+	subscriptions, rows, report, err := parse(r, opts)
+	if err != nil {
+		log.Warn("failed to parse csv", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	denied := false
+	for i, subscription := range subscriptions {
+		if err := domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID); err != nil {
+			denied = true
+			report.Valid--
+			report.Errors = append(report.Errors, RowError{Row: rows[i], Column: opts.Mapping[FieldUserID], Error: err.Error()})
+		}
+	}
+	if len(report.Errors) > 0 {
+		slices.SortStableFunc(report.Errors, func(a, b RowError) int { return a.Row - b.Row })
+		return report, rejected(log, op, denied, len(report.Errors))
+	}
+	if opts.DryRun || len(subscriptions) == 0 {
+		log.Info("csv validated", slog.Int("rows", report.Rows))
+		return report, nil
+	}
+
+	results, err := im.subscriptionService.BulkCreate(ctx, subscriptions, domain.BulkModeAtomic)
+	if errors.Is(err, domain.ErrBulkAborted) {
+		for _, result := range results {
+			if result.Err == nil || errors.Is(result.Err, domain.ErrBulkAborted) {
+				continue
+			}
+			denied = denied || errors.Is(result.Err, domain.ErrForbidden)
+			report.Valid--
+			report.Errors = append(report.Errors, RowError{Row: rows[result.Index], Error: result.Err.Error()})
+		}
+		return report, rejected(log, op, denied, len(report.Errors))
+	}
+	if err != nil {
+		log.Error("failed to import subscriptions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, result := range results {
+		report.IDs = append(report.IDs, result.ID)
+	}
+	report.Imported = len(results)
+	log.Info("subscriptions imported", slog.Int("imported", report.Imported))
+	return report, nil
+}
+
+// rejected returns the error of an import with row errors, denied rows take precedence.
+func rejected(log *slog.Logger, op string, denied bool, errs int) error {
+	if denied {
+		log.Warn("csv contains rows of other users", slog.Int("errors", errs))
+		return fmt.Errorf("%s: %w: csv contains rows of other users", op, domain.ErrForbidden)
+	}
+	log.Warn("csv contains invalid rows", slog.Int("errors", errs))
+	return fmt.Errorf("%s: %w", op, ErrInvalidRows)
+}
+
+// parse returns the valid subscriptions with the line numbers of their rows.
+func parse(r io.Reader, opts Options) ([]*domain.Subscription, []int, *Report, error) {
+	mapping := opts.Mapping
+	if mapping == nil {
+		mapping = DefaultMapping()
+	}
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidCSV, err)
+	}
+	columns, err := mapping.columns(header)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	var subscriptions []*domain.Subscription
+	var rows []int
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		report.Rows++
+		if report.Rows > MaxRows {
+			return nil, nil, nil, fmt.Errorf("%w: more than %d rows", ErrInvalidCSV, MaxRows)
+		}
+		row, _ := reader.FieldPos(0)
+		subscription, rowErr := parseRow(record, columns, mapping)
+		if rowErr != nil {
+			rowErr.Row = row
+			report.Errors = append(report.Errors, *rowErr)
+			continue
+		}
+		if err := subscription.Validate(); err != nil {
+			report.Errors = append(report.Errors, RowError{Row: row, Error: err.Error()})
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+		rows = append(rows, row)
+	}
+	report.Valid = len(subscriptions)
+	return subscriptions, rows, report, nil
+}
+
+func parseRow(record []string, columns map[string]int, mapping Mapping) (*domain.Subscription, *RowError) {
+	value := func(field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	fail := func(field, message string) *RowError {
+		return &RowError{Column: mapping[field], Error: message}
+	}
+
+	price, err := parsePrice(value(FieldPrice))
+	if err != nil {
+		return nil, fail(FieldPrice, err.Error())
+	}
+	userID, err := uuid.Parse(value(FieldUserID))
+	if err != nil {
+		return nil, fail(FieldUserID, "invalid user_id")
+	}
+	startDate, err := parseDate(value(FieldStartDate))
+	if err != nil {
+		return nil, fail(FieldStartDate, err.Error())
+	}
+	var endDate *domain.MonthYear
+	if raw := value(FieldEndDate); raw != "" {
+		parsed, err := parseDate(raw)
+		if err != nil {
+			return nil, fail(FieldEndDate, err.Error())
+		}
+		endDate = &parsed
+	}
+	return &domain.Subscription{
+		ServiceName: value(FieldServiceName),
+		Price:       price,
+		UserID:      userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+	}, nil
+}
+
+func parsePrice(s string) (int, error) {
+	price, err := money.ParseWhole(s)
+	if errors.Is(err, money.ErrNotWhole) {
+		return 0, errors.New("price should be a whole number")
+	}
+	if err != nil || price > math.MaxInt32 {
+		return 0, errors.New("price should be a number")
+	}
+	return int(price), nil
+}
+
+// parseDate accepts MM-YYYY as well as ISO YYYY-MM and YYYY-MM-DD dates.
+func parseDate(s string) (domain.MonthYear, error) {
+	if match := isoMonthPattern.FindStringSubmatch(s); match != nil {
+		s = match[2] + "-" + match[1]
+	}
+	return domain.ParseMonthYear(s)
+}
@@ -0,0 +1,109 @@
+package csvimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// bulkInteractor creates subscriptions like the interactor in atomic mode: items named in
+// fail get their error and every other item is rolled back.
+type bulkInteractor struct {
+	domain.SubscriptionInteractor
+	fail    map[int]error
+	created int
+}
+
+func (i *bulkInteractor) BulkCreate(ctx context.Context, subscriptions []*domain.Subscription, mode domain.BulkMode) ([]domain.BulkResult, error) {
+	results := make([]domain.BulkResult, len(subscriptions))
+	aborted := false
+	for n, subscription := range subscriptions {
+		results[n].Index = n
+		results[n].Err = i.fail[n]
+		if results[n].Err == nil {
+			results[n].Err = domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID)
+		}
+		aborted = aborted || results[n].Err != nil
+	}
+	if aborted {
+		for n := range results {
+			if results[n].Err == nil {
+				results[n].Err = domain.ErrBulkAborted
+			}
+		}
+		return results, fmt.Errorf("service.subscription.bulkCreate: %w", domain.ErrBulkAborted)
+	}
+	for n := range results {
+		results[n].ID = uuid.New()
+	}
+	i.created += len(subscriptions)
+	return results, nil
+}
+
+func TestImportOtherUser(t *testing.T) {
+	own, other := uuid.New(), uuid.New()
+	csv := "service_name,price,user_id,start_date\n" +
+		"Yandex Plus,400," + own.String() + ",07-2025\n" +
+		"Okko,300," + other.String() + ",01-2026\n"
+	ctx := domain.WithPrincipal(context.Background(), domain.UserPrincipal(own, domain.RoleUser, nil))
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry_run=%t", dryRun), func(t *testing.T) {
+			interactor := &bulkInteractor{}
+			importer := NewImporter(slog.New(slog.NewTextHandler(io.Discard, nil)), interactor)
+			report, err := importer.Import(ctx, strings.NewReader(csv), Options{DryRun: dryRun})
+			if !errors.Is(err, domain.ErrForbidden) {
+				t.Fatalf("Import() error = %v, want %v", err, domain.ErrForbidden)
+			}
+			if len(report.Errors) != 1 || report.Errors[0].Row != 3 || report.Errors[0].Column != FieldUserID {
+				t.Errorf("Import() errors = %+v, want one on row 3 in user_id", report.Errors)
+			}
+			if report.Valid != 1 || interactor.created != 0 {
+				t.Errorf("Import() valid = %d, created %d, want 1 and 0", report.Valid, interactor.created)
+			}
+		})
+	}
+}
+
+func TestImportBulkErrors(t *testing.T) {
+	userID := uuid.New().String()
+	csv := "service_name,price,user_id,start_date\n" +
+		"Yandex Plus,400," + userID + ",07-2025\n" +
+		"Okko,300," + userID + ",01-2026\n" +
+		"Kinopoisk,500," + userID + ",03-2026\n"
+	interactor := &bulkInteractor{fail: map[int]error{1: errors.New("duplicate subscription")}}
+	importer := NewImporter(slog.New(slog.NewTextHandler(io.Discard, nil)), interactor)
+	report, err := importer.Import(context.Background(), strings.NewReader(csv), Options{})
+	if !errors.Is(err, ErrInvalidRows) {
+		t.Fatalf("Import() error = %v, want %v", err, ErrInvalidRows)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 3 || report.Errors[0].Error != "duplicate subscription" {
+		t.Errorf("Import() errors = %+v, want the duplicate on row 3", report.Errors)
+	}
+	if report.Imported != 0 || len(report.IDs) != 0 {
+		t.Errorf("Import() imported = %d, ids %v, want none", report.Imported, report.IDs)
+	}
+}
+
+func TestImport(t *testing.T) {
+	userID := uuid.New()
+	csv := "service_name,price,user_id,start_date,end_date\n" +
+		"Yandex Plus,400," + userID.String() + ",2025-07,\n" +
+		"Okko,300," + userID.String() + ",01-2026,12-2026\n"
+	ctx := domain.WithPrincipal(context.Background(), domain.UserPrincipal(userID, domain.RoleUser, nil))
+	interactor := &bulkInteractor{}
+	importer := NewImporter(slog.New(slog.NewTextHandler(io.Discard, nil)), interactor)
+	report, err := importer.Import(ctx, strings.NewReader(csv), Options{})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if report.Rows != 2 || report.Valid != 2 || report.Imported != 2 || len(report.IDs) != 2 {
+		t.Errorf("Import() report = %+v, want 2 rows imported", report)
+	}
+}
@@ -0,0 +1,73 @@
+package csvimport
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	FieldServiceName = "service_name"
+	FieldPrice       = "price"
+	FieldUserID      = "user_id"
+	FieldStartDate   = "start_date"
+	FieldEndDate     = "end_date"
+)
+
+var requiredFields = []string{FieldServiceName, FieldPrice, FieldUserID, FieldStartDate}
+
+// Mapping maps subscription fields to CSV header names.
+type Mapping map[string]string
+
+func DefaultMapping() Mapping {
+	return Mapping{
+		FieldServiceName: FieldServiceName,
+		FieldPrice:       FieldPrice,
+		FieldUserID:      FieldUserID,
+		FieldStartDate:   FieldStartDate,
+		FieldEndDate:     FieldEndDate,
+	}
+}
+
+// ParseMapping parses "field=Column,field=Column" overrides on top of DefaultMapping,
+// e.g. "service_name=Service,price=Monthly cost".
+func ParseMapping(s string) (Mapping, error) {
+	mapping := DefaultMapping()
+	if strings.TrimSpace(s) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("incorrect mapping %q. Expecting field=Column", pair)
+		}
+		if _, known := mapping[field]; !known {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+func (m Mapping) columns(header []string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	columns := make(map[string]int, len(m))
+	for field, column := range m {
+		if i, ok := positions[strings.ToLower(column)]; ok {
+			columns[field] = i
+		}
+	}
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("column %q for %s not found in header", m[field], field)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,190 @@
+package eventstream
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const pageSize = 500
+
+var ErrHubStopped = errors.New("event stream is stopped")
+
+// Hub polls the event log once for all listeners and fans new events out to them.
+type Hub struct {
+	log          *slog.Logger
+	events       domain.EventLog
+	pollInterval time.Duration
+	buffer       int
+
+	ready       chan struct{}
+	mu          sync.Mutex
+	last        int64
+	stopped     bool
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events published after it was created. A subscriber that
+// does not keep up is dropped and its channel is closed, it should reconnect and
+// replay from the last sequence it has seen.
+type Subscription struct {
+	hub      *Hub
+	userID   *uuid.UUID
+	ch       chan domain.StreamEvent
+	position int64
+	once     sync.Once
+}
+
+func NewHub(log *slog.Logger, events domain.EventLog, pollInterval time.Duration, buffer int) *Hub {
+	return &Hub{
+		log:          log,
+		events:       events,
+		pollInterval: pollInterval,
+		buffer:       buffer,
+		ready:        make(chan struct{}),
+		subscribers:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Run follows the event log until ctx is canceled.
+func (h *Hub) Run(ctx context.Context) {
+	const op = "service.eventstream.run"
+	log := h.log.With(slog.String("op", op))
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		last, err := h.events.LastSequence(ctx)
+		if err == nil {
+			h.last = last
+			close(h.ready)
+			break
+		}
+		if ctx.Err() == nil {
+			log.Error("failed to get last event sequence", sl.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+	defer h.closeAll()
+
+	for {
+		for {
+			h.mu.Lock()
+			after := h.last
+			h.mu.Unlock()
+			events, err := h.events.EventsAfter(ctx, after, nil, pageSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error("failed to poll event log", sl.Err(err))
+				}
+				break
+			}
+			h.broadcast(events)
+			if len(events) < pageSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Subscribe registers a listener for the events of userID, or of all users when nil.
+func (h *Hub) Subscribe(ctx context.Context, userID *uuid.UUID) (*Subscription, error) {
+	select {
+	case <-h.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	sub := &Subscription{hub: h, userID: userID, ch: make(chan domain.StreamEvent, h.buffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return nil, ErrHubStopped
+	}
+	sub.position = h.last
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+// Replay calls fn for the stored events after sequence that were published before
+// the subscription was created. Together with Events it yields every event once.
+func (s *Subscription) Replay(ctx context.Context, after int64, fn func(domain.StreamEvent) error) error {
+	for after < s.position {
+		events, err := s.hub.events.EventsAfter(ctx, after, s.userID, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, event := range events {
+			if event.Sequence > s.position {
+				return nil
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+			after = event.Sequence
+		}
+	}
+	return nil
+}
+
+func (s *Subscription) Events() <-chan domain.StreamEvent {
+	return s.ch
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (h *Hub) broadcast(events []domain.StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, event := range events {
+		for sub := range h.subscribers {
+			if sub.userID != nil && (event.Event.Subscription == nil || event.Event.Subscription.UserID != *sub.userID) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				h.log.Warn("dropping slow event stream subscriber")
+				h.remove(sub)
+			}
+		}
+		h.last = event.Sequence
+	}
+}
+
+// remove should be called with h.mu held.
+func (h *Hub) remove(sub *Subscription) {
+	sub.once.Do(func() {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	})
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
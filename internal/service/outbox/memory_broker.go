@@ -0,0 +1,64 @@
+package outbox
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+type BrokerMessage struct {
+	Subject string
+	Key     string
+	Data    []byte
+}
+
+// MemoryBroker is an in-process Broker for local runs and tests. Publish blocks
+// until every matching subscriber received the message or ctx is done.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*memorySubscriber]struct{}
+}
+
+type memorySubscriber struct {
+	prefix string
+	ch     chan BrokerMessage
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[*memorySubscriber]struct{})}
+}
+
+// Subscribe returns messages whose subject starts with prefix and a function that
+// cancels the subscription.
+func (b *MemoryBroker) Subscribe(prefix string, buffer int) (<-chan BrokerMessage, func()) {
+	sub := &memorySubscriber{prefix: prefix, ch: make(chan BrokerMessage, buffer)}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, subject, key string, data []byte) error {
+	message := BrokerMessage{Subject: subject, Key: key, Data: append([]byte(nil), data...)}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if !strings.HasPrefix(subject, sub.prefix) {
+			continue
+		}
+		select {
+		case sub.ch <- message:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
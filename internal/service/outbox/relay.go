@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+// Sink receives every event stored in the outbox.
+type Sink struct {
+	Name      string
+	Publisher domain.EventPublisher
+}
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Published events are kept for Retention before they are purged.
+	Retention time.Duration
+}
+
+// Relay moves events from the outbox to the sinks. Delivery is at least once: a batch
+// is marked published only after every sink accepted it, a failing sink makes the
+// whole batch be retried, so sinks should deduplicate by Event.ID.
+type Relay struct {
+	log   *slog.Logger
+	repo  domain.OutboxRepository
+	tx    domain.Transactor
+	sinks []Sink
+	cfg   RelayConfig
+}
+
+func NewRelay(log *slog.Logger, repo domain.OutboxRepository, tx domain.Transactor, cfg RelayConfig, sinks ...Sink) *Relay {
+	return &Relay{log: log, repo: repo, tx: tx, sinks: sinks, cfg: cfg}
+}
+
+// Run relays events until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+	var purged time.Time
+	for {
+		relayed, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error("failed to relay outbox events", slog.String("op", "service.outbox.relay"), sl.Err(err))
+		}
+		if err == nil && relayed == r.cfg.BatchSize {
+			continue
+		}
+		if time.Since(purged) > time.Hour {
+			r.purge(ctx)
+			purged = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	relayed := 0
+	err := r.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		messages, err := r.repo.ClaimPending(ctx, r.cfg.BatchSize)
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+		events := make([]domain.Event, len(messages))
+		ids := make([]int64, len(messages))
+		for i, message := range messages {
+			events[i] = message.Event
+			ids[i] = message.ID
+		}
+		for _, sink := range r.sinks {
+			if err := sink.Publisher.Publish(ctx, events...); err != nil {
+				return fmt.Errorf("sink %s: %w", sink.Name, err)
+			}
+		}
+		relayed = len(messages)
+		return r.repo.MarkPublished(ctx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if relayed > 0 {
+		r.log.Debug("outbox events relayed", slog.Int("events", relayed))
+	}
+	return relayed, nil
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	deleted, err := r.repo.DeletePublished(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Error("failed to purge outbox events", slog.String("op", "service.outbox.purge"), sl.Err(err))
+		}
+		return
+	}
+	r.log.Debug("published outbox events purged", slog.Int64("deleted", deleted))
+}
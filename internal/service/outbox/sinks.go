@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// LogSink writes every event to the log.
+type LogSink struct {
+	log *slog.Logger
+}
+
+func NewLogSink(log *slog.Logger) *LogSink {
+	return &LogSink{log: log}
+}
+
+func (s *LogSink) Publish(ctx context.Context, events ...domain.Event) error {
+	for _, event := range events {
+		attrs := []any{
+			slog.String("event_id", event.ID),
+			slog.String("type", string(event.Type)),
+		}
+		if event.Subscription != nil {
+			attrs = append(attrs,
+				slog.String("subscription_id", event.Subscription.ID.String()),
+				slog.String("user_id", event.Subscription.UserID.String()),
+			)
+		}
+		s.log.InfoContext(ctx, "subscription event", attrs...)
+	}
+	return nil
+}
+
+// Broker is the producer side of a message broker. It matches both NATS (subject,
+// data) and Kafka (topic, key, value), key is used for partitioning where supported.
+type Broker interface {
+	Publish(ctx context.Context, subject, key string, data []byte) error
+}
+
+// BrokerSink publishes events as JSON to "<prefix>.<event type>", keyed by
+// subscription id so that the events of one subscription stay ordered.
+type BrokerSink struct {
+	broker Broker
+	prefix string
+}
+
+func NewBrokerSink(broker Broker, prefix string) *BrokerSink {
+	return &BrokerSink{broker: broker, prefix: prefix}
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, events ...domain.Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		var key string
+		if event.Subscription != nil {
+			key = event.Subscription.ID.String()
+		}
+		subject := string(event.Type)
+		if s.prefix != "" {
+			subject = s.prefix + "." + subject
+		}
+		if err := s.broker.Publish(ctx, subject, key, data); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", event.ID, err)
+		}
+	}
+	return nil
+}
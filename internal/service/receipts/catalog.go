@@ -0,0 +1,119 @@
+package receipts
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Service describes how receipts of a known subscription service look like.
+// Senders are addresses or domains, a domain also matches its subdomains.
+type Service struct {
+	Name     string   `yaml:"name"`
+	Senders  []string `yaml:"senders"`
+	Subjects []string `yaml:"subjects"`
+	// Annual prices are spread over 12 months the way TotalCost accounts for them.
+	Annual bool `yaml:"annual"`
+
+	subjects []*regexp.Regexp
+}
+
+type Catalog struct {
+	Services []Service `yaml:"services"`
+}
+
+// DefaultCatalog returns the services recognized when no catalog file is given.
+func DefaultCatalog() *Catalog {
+	c := &Catalog{Services: []Service{
+		{Name: "Yandex Plus", Senders: []string{"plus.yandex.ru", "yandex-team.ru", "yandex.ru"}, Subjects: []string{`(?i)плюс`, `(?i)yandex plus`}},
+		{Name: "Кинопоиск", Senders: []string{"kinopoisk.ru"}, Subjects: []string{`(?i)подписк|оплат|чек`}},
+		{Name: "VK Музыка", Senders: []string{"vk.com", "vk.ru"}, Subjects: []string{`(?i)vk (музыка|combo)|подписк`}},
+		{Name: "Okko", Senders: []string{"okko.tv"}, Subjects: []string{`(?i)подписк|оплат|чек`}},
+		{Name: "ivi", Senders: []string{"ivi.ru"}, Subjects: []string{`(?i)подписк|оплат|чек`}},
+		{Name: "Netflix", Senders: []string{"netflix.com"}, Subjects: []string{`(?i)receipt|payment|membership`}},
+		{Name: "Spotify", Senders: []string{"spotify.com"}, Subjects: []string{`(?i)receipt|premium`}},
+		{Name: "YouTube Premium", Senders: []string{"youtube.com"}, Subjects: []string{`(?i)youtube premium`}},
+		{Name: "Apple", Senders: []string{"email.apple.com", "apple.com"}, Subjects: []string{`(?i)receipt|чек`}},
+		{Name: "GitHub", Senders: []string{"github.com"}, Subjects: []string{`(?i)receipt|payment`}},
+		{Name: "JetBrains", Senders: []string{"jetbrains.com"}, Subjects: []string{`(?i)invoice|receipt|order`}, Annual: true},
+	}}
+	if err := c.compile(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadCatalog reads a YAML catalog file, see DefaultCatalog for the structure.
+func LoadCatalog(path string) (*Catalog, error) {
+	var c Catalog
+	if err := cleanenv.ReadConfig(path, &c); err != nil {
+		return nil, fmt.Errorf("cannot read catalog: %w", err)
+	}
+	if err := c.compile(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *Catalog) compile() error {
+	for i := range c.Services {
+		service := &c.Services[i]
+		if service.Name == "" || len(service.Senders) == 0 {
+			return fmt.Errorf("catalog service #%d should have name and senders", i+1)
+		}
+		service.subjects = service.subjects[:0]
+		for _, pattern := range service.Subjects {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("catalog service %q: incorrect subject pattern: %w", service.Name, err)
+			}
+			service.subjects = append(service.subjects, re)
+		}
+	}
+	return nil
+}
+
+// Match returns the service that sent the message. A sender matching several
+// services is resolved by the most specific sender, subject patterns filter out
+// non-receipt mail such as newsletters.
+func (c *Catalog) Match(msg *Message) (*Service, bool) {
+	var best *Service
+	bestLen := 0
+	for i := range c.Services {
+		service := &c.Services[i]
+		sender := matchSender(service.Senders, msg.From)
+		if sender == 0 || sender <= bestLen || !service.matchSubject(msg.Subject) {
+			continue
+		}
+		best, bestLen = service, sender
+	}
+	return best, best != nil
+}
+
+// matchSender returns the length of the matching sender, 0 if none matches.
+func matchSender(senders []string, from string) int {
+	_, domain, _ := strings.Cut(from, "@")
+	matched := 0
+	for _, sender := range senders {
+		sender = strings.ToLower(sender)
+		ok := from == sender || domain == sender || strings.HasSuffix(domain, "."+sender)
+		if ok && len(sender) > matched {
+			matched = len(sender)
+		}
+	}
+	return matched
+}
+
+func (s *Service) matchSubject(subject string) bool {
+	if len(s.subjects) == 0 {
+		return true
+	}
+	for _, re := range s.subjects {
+		if re.MatchString(subject) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package receipts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCatalogMatch(t *testing.T) {
+	catalog := DefaultCatalog()
+	tests := []struct {
+		name    string
+		from    string
+		subject string
+		want    string
+	}{
+		{name: "address in a subdomain", from: "info@account.netflix.com", subject: "Your Netflix payment receipt", want: "Netflix"},
+		{name: "exact domain", from: "no-reply@spotify.com", subject: "Your Spotify Premium receipt", want: "Spotify"},
+		{name: "most specific sender", from: "noreply@plus.yandex.ru", subject: "Оплата подписки Яндекс Плюс", want: "Yandex Plus"},
+		{name: "annual plan", from: "sales@jetbrains.com", subject: "Your JetBrains order receipt", want: "JetBrains"},
+		{name: "subject filters out newsletters", from: "info@account.netflix.com", subject: "New on Netflix this week"},
+		{name: "unknown sender", from: "newsletter@shop.example.com", subject: "Your order receipt"},
+		{name: "domain lookalike", from: "billing@notnetflix.com", subject: "Your Netflix payment receipt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, ok := catalog.Match(&Message{From: tt.from, Subject: tt.subject})
+			got := ""
+			if ok {
+				got = service.Name
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %q) = %q, want %q", tt.from, tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogMatchFixtures(t *testing.T) {
+	catalog := DefaultCatalog()
+	tests := []struct {
+		file   string
+		want   string
+		annual bool
+		amount Amount
+	}{
+		{file: "plain_text.eml", want: "Netflix", amount: Amount{Value: 1549, Currency: "USD"}},
+		{file: "multipart_alternative.eml", want: "Spotify", amount: Amount{Value: 16900, Currency: "RUB"}},
+		{file: "quoted_printable.eml", want: "Yandex Plus", amount: Amount{Value: 39900, Currency: "RUB"}},
+		{file: "annual_plan.eml", want: "JetBrains", annual: true, amount: Amount{Value: 16900, Currency: "EUR"}},
+		{file: "unknown_sender.eml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			msg, err := ParseEML(openFixture(t, tt.file))
+			if err != nil {
+				t.Fatalf("ParseEML() error = %v", err)
+			}
+			service, ok := catalog.Match(msg)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("Match() = %q, want no service", service.Name)
+				}
+				return
+			}
+			if !ok || service.Name != tt.want || service.Annual != tt.annual {
+				t.Fatalf("Match() = %+v, %v, want %q (annual %v)", service, ok, tt.want, tt.annual)
+			}
+			amount, ok := ExtractAmount(msg.Subject + "\n" + msg.Text)
+			if !ok || amount != tt.amount {
+				t.Errorf("ExtractAmount() = %+v, %v, want %+v", amount, ok, tt.amount)
+			}
+		})
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "catalog.yaml")
+	os.WriteFile(valid, []byte("services:\n  - name: Kinopoisk\n    senders: [kinopoisk.ru]\n    subjects: [\"(?i)чек\"]\n"), 0o644)
+	noSenders := filepath.Join(dir, "no_senders.yaml")
+	os.WriteFile(noSenders, []byte("services:\n  - name: Kinopoisk\n"), 0o644)
+	badPattern := filepath.Join(dir, "bad_pattern.yaml")
+	os.WriteFile(badPattern, []byte("services:\n  - name: Kinopoisk\n    senders: [kinopoisk.ru]\n    subjects: [\"(\"]\n"), 0o644)
+
+	catalog, err := LoadCatalog(valid)
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+	if service, ok := catalog.Match(&Message{From: "noreply@kinopoisk.ru", Subject: "Ваш чек"}); !ok || service.Name != "Kinopoisk" {
+		t.Errorf("Match() = %+v, %v, want Kinopoisk", service, ok)
+	}
+	for _, path := range []string{noSenders, badPattern, filepath.Join(dir, "missing.yaml")} {
+		if _, err := LoadCatalog(path); err == nil {
+			t.Errorf("LoadCatalog(%s) error = nil", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package receipts
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/money"
+)
+
+const amountPattern = `(\d{1,3}(?:[ \x{00a0},]\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?)`
+
+var (
+	currencyAliases = map[string]string{
+		"₽": "RUB", "руб": "RUB", "руб.": "RUB", "р.": "RUB", "rub": "RUB",
+		"$": "USD", "usd": "USD",
+		"€": "EUR", "eur": "EUR",
+	}
+	amountBefore = regexp.MustCompile(`(?i)(?:^|[^\d.,])` + amountPattern + `\s?(₽|руб\.?|р\.|rub\b|usd\b|eur\b|€|\$)`)
+	amountAfter  = regexp.MustCompile(`(?i)(₽|rub|usd|eur|€|\$)\s?` + amountPattern)
+	// totalWords mark lines holding the charged amount rather than line items or taxes.
+	totalWords = []string{"итого", "к оплате", "списано", "сумма", "оплачено", "total", "amount", "charged", "paid"}
+)
+
+// Amount is a charge found in a receipt, Value is in minor units.
+type Amount struct {
+	Value    int64
+	Currency string
+}
+
+// ExtractAmount returns the charged amount: the first amount on a line mentioning a
+// total, otherwise the first amount with a currency in the text.
+func ExtractAmount(text string) (Amount, bool) {
+	var first Amount
+	found := false
+	for _, line := range strings.Split(text, "\n") {
+		amount, ok := lineAmount(line)
+		if !ok {
+			continue
+		}
+		lower := strings.ToLower(line)
+		for _, word := range totalWords {
+			if strings.Contains(lower, word) {
+				return amount, true
+			}
+		}
+		if !found {
+			first, found = amount, true
+		}
+	}
+	return first, found
+}
+
+func lineAmount(line string) (Amount, bool) {
+	var value, currency string
+	before := amountBefore.FindStringSubmatchIndex(line)
+	after := amountAfter.FindStringSubmatchIndex(line)
+	switch {
+	case before != nil && (after == nil || before[0] <= after[0]):
+		value, currency = line[before[2]:before[3]], line[before[4]:before[5]]
+	case after != nil:
+		value, currency = line[after[4]:after[5]], line[after[2]:after[3]]
+	default:
+		return Amount{}, false
+	}
+	minor, err := money.ParseMinor(value)
+	if err != nil || minor <= 0 {
+		return Amount{}, false
+	}
+	return Amount{Value: minor, Currency: currencyAliases[strings.ToLower(currency)]}, true
+}
@@ -0,0 +1,43 @@
+package receipts
+
+import "testing"
+
+func TestExtractAmount(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Amount
+		ok   bool
+	}{
+		{name: "rubles after the number", text: "Итого: 399 ₽", want: Amount{Value: 39900, Currency: "RUB"}, ok: true},
+		{name: "decimal comma", text: "Списано 299,50 руб.", want: Amount{Value: 29950, Currency: "RUB"}, ok: true},
+		{name: "thousands with spaces", text: "Сумма: 1 299,00 ₽", want: Amount{Value: 129900, Currency: "RUB"}, ok: true},
+		{name: "thousands with no-break space", text: "Сумма: 1\u00a0299 р.", want: Amount{Value: 129900, Currency: "RUB"}, ok: true},
+		{name: "dollars before the number", text: "Total: $15.49", want: Amount{Value: 1549, Currency: "USD"}, ok: true},
+		{name: "thousands with comma", text: "Total: $1,234.50", want: Amount{Value: 123450, Currency: "USD"}, ok: true},
+		{name: "currency code", text: "Amount paid: EUR 169.00", want: Amount{Value: 16900, Currency: "EUR"}, ok: true},
+		{
+			name: "total line wins over line items",
+			text: "Plan     $12.99\nTax       $2.00\nTotal charged: $14.99",
+			want: Amount{Value: 1499, Currency: "USD"},
+			ok:   true,
+		},
+		{
+			name: "first amount without a total line",
+			text: "Plan     $12.99\nTax       $2.00",
+			want: Amount{Value: 1299, Currency: "USD"},
+			ok:   true,
+		},
+		{name: "number without currency", text: "Order 1234567, total 42", ok: false},
+		{name: "zero amount", text: "Total: 0 ₽", ok: false},
+		{name: "empty", text: "", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractAmount(tt.text)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ExtractAmount(%q) = %+v, %v, want %+v, %v", tt.text, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
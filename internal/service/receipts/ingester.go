@@ -0,0 +1,285 @@
+package receipts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const MaxMessages = 5000
+
+var ErrInvalidMailbox = errors.New("invalid mailbox")
+
+type Action string
+
+const (
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionUnchanged Action = "unchanged"
+)
+
+type Options struct {
+	DryRun bool
+}
+
+// Receipt is a recognized message. Price is monthly in whole currency units.
+type Receipt struct {
+	Message     int       `json:"message"`
+	ServiceName string    `json:"service_name"`
+	Price       int       `json:"price"`
+	Currency    string    `json:"currency,omitempty"`
+	ChargedAt   time.Time `json:"charged_at"`
+}
+
+type Skipped struct {
+	Message int    `json:"message"`
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type Change struct {
+	Action         Action           `json:"action"`
+	SubscriptionID uuid.UUID        `json:"subscription_id,omitempty"`
+	ServiceName    string           `json:"service_name"`
+	Price          int              `json:"price"`
+	PreviousPrice  int              `json:"previous_price,omitempty"`
+	StartDate      domain.MonthYear `json:"start_date"`
+	Receipts       int              `json:"receipts"`
+}
+
+type Report struct {
+	DryRun   bool      `json:"dry_run"`
+	Messages int       `json:"messages"`
+	Receipts []Receipt `json:"receipts"`
+	Skipped  []Skipped `json:"skipped,omitempty"`
+	Changes  []Change  `json:"changes"`
+}
+
+type Ingester struct {
+	log                 *slog.Logger
+	catalog             *Catalog
+	subscriptionService domain.SubscriptionInteractor
+	tx                  domain.Transactor
+}
+
+func NewIngester(log *slog.Logger, catalog *Catalog, subscriptionService domain.SubscriptionInteractor, tx domain.Transactor) *Ingester {
+	return &Ingester{log: log, catalog: catalog, subscriptionService: subscriptionService, tx: tx}
+}
+
+// Ingest reads an mbox file or a single .eml message and brings the user's
+// subscriptions in line with the recognized receipts: a service without an active
+// subscription is created starting from its earliest receipt, an active one gets
+// the price of the latest receipt. Unrecognized messages are reported as skipped.
+func (in *Ingester) Ingest(ctx context.Context, userID uuid.UUID, r io.Reader, opts Options) (*Report, error) {
+	const op = "service.receipts.ingest"
+	log := sl.LoggerFromContext(ctx, in.log).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+		slog.Bool("dry_run", opts.DryRun),
+	)
+	log.Info("ingesting receipts")
+
+	report := &Report{DryRun: opts.DryRun, Receipts: []Receipt{}, Changes: []Change{}}
+	err := readMessages(r, func(raw []byte) error {
+		report.Messages++
+		if report.Messages > MaxMessages {
+			return fmt.Errorf("%w: more than %d messages", ErrInvalidMailbox, MaxMessages)
+		}
+		in.recognize(report, raw)
+		return nil
+	})
+	if err != nil {
+		log.Warn("failed to read mailbox", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	changes, err := in.plan(ctx, userID, report.Receipts)
+	if err != nil {
+		log.Error("failed to get user subscriptions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !opts.DryRun {
+		if err := in.apply(ctx, userID, changes); err != nil {
+			log.Error("failed to apply receipts", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	for _, change := range changes {
+		report.Changes = append(report.Changes, change.Change)
+	}
+	log.Info("receipts ingested",
+		slog.Int("messages", report.Messages),
+		slog.Int("receipts", len(report.Receipts)),
+		slog.Int("changes", len(report.Changes)),
+	)
+	return report, nil
+}
+
+func (in *Ingester) recognize(report *Report, raw []byte) {
+	index := report.Messages
+	msg, err := ParseEML(bytes.NewReader(raw))
+	if err != nil {
+		report.Skipped = append(report.Skipped, Skipped{Message: index, Reason: err.Error()})
+		return
+	}
+	skip := func(reason string) {
+		report.Skipped = append(report.Skipped, Skipped{Message: index, From: msg.From, Subject: msg.Subject, Reason: reason})
+	}
+	service, ok := in.catalog.Match(msg)
+	if !ok {
+		skip("unknown sender")
+		return
+	}
+	amount, ok := ExtractAmount(msg.Subject + "\n" + msg.Text)
+	if !ok {
+		skip("no amount found")
+		return
+	}
+	price := float64(amount.Value) / 100
+	if service.Annual {
+		price /= 12
+	}
+	report.Receipts = append(report.Receipts, Receipt{
+		Message:     index,
+		ServiceName: service.Name,
+		Price:       int(math.Round(price)),
+		Currency:    amount.Currency,
+		ChargedAt:   msg.Date,
+	})
+}
+
+type plannedChange struct {
+	Change
+	version int
+}
+
+func (in *Ingester) plan(ctx context.Context, userID uuid.UUID, receipts []Receipt) ([]plannedChange, error) {
+	byService := make(map[string][]Receipt)
+	var names []string
+	for _, receipt := range receipts {
+		key := strings.ToLower(receipt.ServiceName)
+		if _, ok := byService[key]; !ok {
+			names = append(names, key)
+		}
+		byService[key] = append(byService[key], receipt)
+	}
+	sort.Strings(names)
+
+	active := make(map[string]*domain.Subscription)
+	current := domain.FromTime(time.Now())
+	err := in.subscriptionService.ExportSubscriptions(ctx, domain.SubscriptionFilter{UserID: &userID}, func(subscription *domain.Subscription) error {
+		if subscription.EndDate != nil && subscription.EndDate.IsBefore(current) {
+			return nil
+		}
+		key := strings.ToLower(subscription.ServiceName)
+		if _, ok := byService[key]; ok {
+			active[key] = subscription
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]plannedChange, 0, len(names))
+	for _, key := range names {
+		group := byService[key]
+		sort.Slice(group, func(i, j int) bool { return group[i].ChargedAt.Before(group[j].ChargedAt) })
+		first, latest := group[0], group[len(group)-1]
+		change := plannedChange{Change: Change{
+			ServiceName: latest.ServiceName,
+			Price:       latest.Price,
+			StartDate:   domain.FromTime(first.ChargedAt),
+			Receipts:    len(group),
+		}}
+		subscription, ok := active[key]
+		switch {
+		case !ok:
+			change.Action = ActionCreated
+		case subscription.Price != latest.Price:
+			change.Action = ActionUpdated
+			change.SubscriptionID = subscription.ID
+			change.PreviousPrice = subscription.Price
+			change.StartDate = subscription.StartDate
+			change.version = subscription.Version
+		default:
+			change.Action = ActionUnchanged
+			change.SubscriptionID = subscription.ID
+			change.StartDate = subscription.StartDate
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// apply makes all changes in one transaction, a failure leaves the subscriptions as
+// they were before the mailbox was ingested.
+func (in *Ingester) apply(ctx context.Context, userID uuid.UUID, changes []plannedChange) error {
+	return in.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var created []*domain.Subscription
+		var positions []int
+		for i := range changes {
+			change := &changes[i]
+			switch change.Action {
+			case ActionCreated:
+				created = append(created, &domain.Subscription{
+					ServiceName: change.ServiceName,
+					Price:       change.Price,
+					UserID:      userID,
+					StartDate:   change.StartDate,
+				})
+				positions = append(positions, i)
+			case ActionUpdated:
+				price := change.Price
+				if _, err := in.subscriptionService.PatchSubscription(ctx, change.SubscriptionID, domain.SubscriptionPatch{Price: &price}, change.version); err != nil {
+					return err
+				}
+			}
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		results, err := in.subscriptionService.BulkCreate(ctx, created, domain.BulkModeAtomic)
+		if err != nil {
+			return err
+		}
+		for i, result := range results {
+			changes[positions[i]].SubscriptionID = result.ID
+		}
+		return nil
+	})
+}
+
+// readMessages treats input starting with an mbox "From " separator line as a
+// mailbox and anything else as a single message.
+func readMessages(r io.Reader, fn func(raw []byte) error) error {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(5)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if string(head) == "From " {
+		return SplitMbox(br, fn)
+	}
+	raw, err := io.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return fmt.Errorf("%w: empty input", ErrInvalidMailbox)
+	}
+	return fn(raw)
+}
@@ -0,0 +1,197 @@
+package receipts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Message is the part of an email needed to recognize a receipt.
+type Message struct {
+	From    string
+	Subject string
+	Date    time.Time
+	Text    string
+}
+
+var (
+	wordDecoder   = &mime.WordDecoder{CharsetReader: charsetReader}
+	htmlBlocks    = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	htmlBreaks    = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/li|/h\d)[^>]*>`)
+	htmlTags      = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceSequence = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// ParseEML parses a single RFC 5322 message. Plain text parts are preferred, HTML
+// parts are reduced to text when the message has nothing else.
+func ParseEML(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	from := msg.Header.Get("From")
+	if address, err := (&mail.AddressParser{WordDecoder: wordDecoder}).Parse(from); err == nil {
+		from = address.Address
+	}
+	date, err := msg.Header.Date()
+	if err != nil {
+		return nil, fmt.Errorf("%w: incorrect Date header: %v", ErrInvalidMessage, err)
+	}
+
+	plain, htmlText, err := readPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	text := plain
+	if strings.TrimSpace(text) == "" {
+		text = htmlToText(htmlText)
+	}
+	return &Message{
+		From:    strings.ToLower(from),
+		Subject: subject,
+		Date:    date,
+		Text:    text,
+	}, nil
+}
+
+// SplitMbox calls fn for every message of an mboxrd/mboxo file.
+func SplitMbox(r io.Reader, fn func(raw []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	var current bytes.Buffer
+	started := false
+	flush := func() error {
+		if !started || current.Len() == 0 {
+			return nil
+		}
+		raw := append([]byte(nil), current.Bytes()...)
+		current.Reset()
+		return fn(raw)
+	}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if err := flush(); err != nil {
+				return err
+			}
+			started = true
+			continue
+		}
+		if !started {
+			continue
+		}
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+			line = line[1:]
+		}
+		current.Write(line)
+		current.WriteString("\r\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func readPart(contentType, transferEncoding string, body io.Reader) (plain, htmlText string, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return plain, htmlText, nil
+			}
+			if err != nil {
+				return "", "", err
+			}
+			p, h, err := readPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", "", err
+			}
+			plain += p
+			htmlText += h
+		}
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineStripper{r: body})
+	}
+	if charset := params["charset"]; charset != "" {
+		if body, err = charsetReader(charset, body); err != nil {
+			return "", "", err
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", "", err
+	}
+	if mediaType == "text/html" {
+		return "", string(data), nil
+	}
+	return string(data), "", nil
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func htmlToText(s string) string {
+	s = htmlBlocks.ReplaceAllString(s, " ")
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return spaceSequence.ReplaceAllString(s, " ")
+}
+
+// newlineStripper drops line breaks that base64 bodies are wrapped with.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n *newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	kept := 0
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
@@ -0,0 +1,204 @@
+package receipts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestParseEML(t *testing.T) {
+	tests := []struct {
+		file        string
+		from        string
+		subject     string
+		date        time.Time
+		contains    []string
+		notContains []string
+	}{
+		{
+			file:     "plain_text.eml",
+			from:     "info@account.netflix.com",
+			subject:  "Your Netflix payment receipt",
+			date:     time.Date(2025, time.March, 3, 10, 15, 0, 0, time.UTC),
+			contains: []string{"Total charged:            $15.49"},
+		},
+		{
+			file:        "multipart_alternative.eml",
+			from:        "no-reply@spotify.com",
+			subject:     "Your Spotify Premium receipt",
+			date:        time.Date(2025, time.March, 11, 5, 0, 0, 0, time.UTC),
+			contains:    []string{"Total: 169 ₽"},
+			notContains: []string{"<p>", "color: red"},
+		},
+		{
+			file:     "quoted_printable.eml",
+			from:     "noreply@plus.yandex.ru",
+			subject:  "Оплата подписки Яндекс Плюс",
+			date:     time.Date(2025, time.February, 1, 9, 0, 0, 0, time.UTC),
+			contains: []string{"Подписка Плюс Мульти     399,00 ₽", "Итого к оплате: 399,00 ₽"},
+		},
+		{
+			file:     "annual_plan.eml",
+			from:     "sales@jetbrains.com",
+			subject:  "Your JetBrains order receipt A-1234567",
+			date:     time.Date(2025, time.January, 15, 8, 30, 0, 0, time.UTC),
+			contains: []string{"Amount paid: EUR 169.00"},
+		},
+		{
+			file:     "unknown_sender.eml",
+			from:     "newsletter@shop.example.com",
+			subject:  "Your order receipt",
+			date:     time.Date(2025, time.February, 20, 18, 45, 0, 0, time.UTC),
+			contains: []string{"Total: 1 200 ₽"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			msg, err := ParseEML(openFixture(t, tt.file))
+			if err != nil {
+				t.Fatalf("ParseEML() error = %v", err)
+			}
+			if msg.From != tt.from {
+				t.Errorf("From = %q, want %q", msg.From, tt.from)
+			}
+			if msg.Subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.subject)
+			}
+			if !msg.Date.Equal(tt.date) {
+				t.Errorf("Date = %v, want %v", msg.Date, tt.date)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(msg.Text, s) {
+					t.Errorf("Text doesn't contain %q:\n%s", s, msg.Text)
+				}
+			}
+			for _, s := range tt.notContains {
+				if strings.Contains(msg.Text, s) {
+					t.Errorf("Text contains %q:\n%s", s, msg.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestParseEMLHTMLOnly(t *testing.T) {
+	raw := "From: billing@github.com\r\n" +
+		"Subject: [GitHub] Payment receipt\r\n" +
+		"Date: Mon, 03 Mar 2025 10:15:00 +0000\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<html><body><p>Amount&nbsp;paid</p><div>$4.00</div></body></html>\r\n"
+	msg, err := ParseEML(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("ParseEML() error = %v", err)
+	}
+	if !strings.Contains(msg.Text, "Amount paid") || !strings.Contains(msg.Text, "$4.00") {
+		t.Errorf("Text = %q, want the HTML reduced to text", msg.Text)
+	}
+}
+
+func TestParseEMLInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{name: "no headers", raw: "just some text"},
+		{name: "bad date", raw: "From: a@b.c\r\nDate: yesterday\r\n\r\nbody\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEML(strings.NewReader(tt.raw))
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Errorf("ParseEML() error = %v, want %v", err, ErrInvalidMessage)
+			}
+		})
+	}
+}
+
+func TestSplitMbox(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		subjects []string
+		bodies   []string
+	}{
+		{
+			name:     "fixture",
+			input:    "mailbox.mbox",
+			subjects: []string{"Your Netflix payment receipt", "Your Netflix payment receipt", "Weekly deals"},
+			bodies:   []string{"\r\nFrom now on your plan renews monthly.\r\n", "Total charged: $17.99", "Everything from 99 ₽"},
+		},
+		{
+			name:     "mboxrd quoting",
+			input:    "From a@b.c Mon Mar  3 10:15:00 2025\nSubject: one\n\n>>From here\n>Fromage\n",
+			subjects: []string{"one"},
+			bodies:   []string{"\r\n>From here\r\n>Fromage\r\n"},
+		},
+		{
+			name:     "text before the first separator",
+			input:    "garbage\nFrom a@b.c Mon Mar  3 10:15:00 2025\nSubject: one\n\nbody\n",
+			subjects: []string{"one"},
+			bodies:   []string{"body"},
+		},
+		{
+			name:  "empty",
+			input: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := strings.NewReader(tt.input)
+			if strings.HasSuffix(tt.input, ".mbox") {
+				data, err := os.ReadFile(filepath.Join("testdata", tt.input))
+				if err != nil {
+					t.Fatalf("read fixture: %v", err)
+				}
+				input = strings.NewReader(string(data))
+			}
+			var messages []string
+			err := SplitMbox(input, func(raw []byte) error {
+				messages = append(messages, string(raw))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("SplitMbox() error = %v", err)
+			}
+			if len(messages) != len(tt.subjects) {
+				t.Fatalf("got %d messages, want %d", len(messages), len(tt.subjects))
+			}
+			for i, raw := range messages {
+				if !strings.Contains(raw, "Subject: "+tt.subjects[i]+"\r\n") {
+					t.Errorf("message %d doesn't have subject %q:\n%s", i, tt.subjects[i], raw)
+				}
+				if !strings.Contains(raw, tt.bodies[i]) {
+					t.Errorf("message %d doesn't contain %q:\n%s", i, tt.bodies[i], raw)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitMboxStopsOnError(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := SplitMbox(openFixture(t, "mailbox.mbox"), func([]byte) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("SplitMbox() error = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
@@ -0,0 +1,96 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/money"
+)
+
+// camt.053 structures, tags carry no namespace so every camt.053.001.xx version matches.
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Status      struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	AdditionalInfo string `xml:"AddtlNtryInf"`
+	Details        []struct {
+		Creditor         string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorParty    string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Unstructured     []string `xml:"RmtInf>Ustrd"`
+		AdditionalTxInfo string   `xml:"AddtlTxInf"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+func ParseCAMT053(r io.Reader) ([]Transaction, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	var transactions []Transaction
+	for _, statement := range doc.Statements {
+		for _, entry := range statement.Entries {
+			status := strings.TrimSpace(entry.Status.Value + entry.Status.Code)
+			if status != "" && status != "BOOK" {
+				continue
+			}
+			date := entry.BookingDate.Date
+			if date == "" && len(entry.BookingDate.DateTime) >= 10 {
+				date = entry.BookingDate.DateTime[:10]
+			}
+			parsedDate, err := parseDate(date)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+			}
+			amount, err := money.ParseMinor(entry.Amount.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+			}
+			if entry.CreditDebit == "DBIT" {
+				amount = -amount
+			}
+			transactions = append(transactions, Transaction{
+				Date:        parsedDate,
+				Amount:      amount,
+				Currency:    entry.Amount.Currency,
+				Description: camtDescription(entry),
+			})
+		}
+	}
+	return transactions, nil
+}
+
+func camtDescription(entry camtEntry) string {
+	for _, details := range entry.Details {
+		for _, name := range []string{details.Creditor, details.CreditorParty} {
+			if name = strings.TrimSpace(name); name != "" {
+				return name
+			}
+		}
+	}
+	for _, details := range entry.Details {
+		for _, text := range append(details.Unstructured, details.AdditionalTxInfo) {
+			if text = strings.TrimSpace(text); text != "" {
+				return text
+			}
+		}
+	}
+	return strings.TrimSpace(entry.AdditionalInfo)
+}
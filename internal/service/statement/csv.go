@@ -0,0 +1,136 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/money"
+)
+
+// CSVOptions names the statement columns. Debit and Credit are used by banks that
+// export amounts in two unsigned columns instead of a single signed Amount.
+type CSVOptions struct {
+	Delimiter   rune
+	Date        string
+	Amount      string
+	Debit       string
+	Credit      string
+	Currency    string
+	Description string
+}
+
+func DefaultCSVOptions() CSVOptions {
+	return CSVOptions{
+		Date:        "date",
+		Amount:      "amount",
+		Debit:       "debit",
+		Credit:      "credit",
+		Currency:    "currency",
+		Description: "description",
+	}
+}
+
+func ParseCSV(r io.Reader, opts CSVOptions) ([]Transaction, error) {
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrInvalidStatement, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(name string) int {
+		if i, ok := columns[strings.ToLower(name)]; ok && name != "" {
+			return i
+		}
+		return -1
+	}
+	dateCol, amountCol, debitCol := column(opts.Date), column(opts.Amount), column(opts.Debit)
+	creditCol, currencyCol, descriptionCol := column(opts.Credit), column(opts.Currency), column(opts.Description)
+	if dateCol < 0 || descriptionCol < 0 || (amountCol < 0 && debitCol < 0) {
+		return nil, fmt.Errorf("%w: csv should have %q, %q and %q (or %q) columns", ErrInvalidStatement, opts.Date, opts.Description, opts.Amount, opts.Debit)
+	}
+
+	var transactions []Transaction
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		row, _ := reader.FieldPos(0)
+		value := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		date, err := parseDate(value(dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		var amount int64
+		switch {
+		case amountCol >= 0 && value(amountCol) != "":
+			amount, err = money.ParseMinor(value(amountCol))
+		case value(debitCol) != "":
+			amount, err = money.ParseMinor(value(debitCol))
+			if amount > 0 {
+				amount = -amount
+			}
+		case value(creditCol) != "":
+			amount, err = money.ParseMinor(value(creditCol))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidStatement, row, err)
+		}
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Amount:      amount,
+			Currency:    value(currencyCol),
+			Description: value(descriptionCol),
+		})
+	}
+	return transactions, nil
+}
+
+// ParseCSVMapping parses "date=Дата,amount=Сумма,description=Описание" overrides
+// on top of DefaultCSVOptions.
+func ParseCSVMapping(s string) (CSVOptions, error) {
+	opts := DefaultCSVOptions()
+	if strings.TrimSpace(s) == "" {
+		return opts, nil
+	}
+	fields := map[string]*string{
+		"date":        &opts.Date,
+		"amount":      &opts.Amount,
+		"debit":       &opts.Debit,
+		"credit":      &opts.Credit,
+		"currency":    &opts.Currency,
+		"description": &opts.Description,
+	}
+	for _, pair := range strings.Split(s, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		target, known := fields[strings.TrimSpace(field)]
+		if !ok || !known {
+			return opts, fmt.Errorf("incorrect mapping %q. Expecting field=Column", pair)
+		}
+		*target = strings.TrimSpace(column)
+	}
+	return opts, nil
+}
@@ -0,0 +1,202 @@
+package statement
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+type Cadence string
+
+const (
+	CadenceMonthly Cadence = "monthly"
+	CadenceAnnual  Cadence = "annual"
+)
+
+const (
+	minMonthlyCharges = 3
+	minAnnualCharges  = 2
+	// amountTolerance is the allowed relative deviation from the median charge.
+	amountTolerance = 0.15
+)
+
+var cadenceDays = map[Cadence][2]float64{
+	CadenceMonthly: {26, 35},
+	CadenceAnnual:  {350, 380},
+}
+
+// noiseWords are dropped from descriptions when grouping charges by merchant.
+var noiseWords = map[string]bool{
+	"pos": true, "payment": true, "purchase": true, "card": true, "debit": true,
+	"оплата": true, "покупка": true, "списание": true, "карта": true, "www": true, "com": true, "ru": true,
+}
+
+// Proposal is a detected recurring charge. Price is monthly, annual charges are
+// spread over 12 months the way TotalCost accounts for them.
+type Proposal struct {
+	ServiceName    string            `json:"service_name"`
+	Price          int               `json:"price"`
+	Currency       string            `json:"currency,omitempty"`
+	Cadence        Cadence           `json:"cadence"`
+	StartDate      domain.MonthYear  `json:"start_date"`
+	EndDate        *domain.MonthYear `json:"end_date,omitempty"`
+	Charges        int               `json:"charges"`
+	LastChargeDate string            `json:"last_charge_date"`
+	Confidence     float64           `json:"confidence"`
+	AlreadyTracked bool              `json:"already_tracked"`
+}
+
+// Detect groups debits by merchant and currency and proposes the groups charged with a monthly or
+// annual cadence. A subscription whose charges stopped more than one and a half
+// periods before the statement end gets an EndDate.
+func Detect(transactions []Transaction) []Proposal {
+	groups := make(map[string][]Transaction)
+	names := make(map[string]string)
+	var statementEnd time.Time
+	for _, tx := range transactions {
+		if tx.Date.After(statementEnd) {
+			statementEnd = tx.Date
+		}
+		if tx.Amount >= 0 {
+			continue
+		}
+		key, name := merchantKey(tx.Description)
+		if key == "" {
+			continue
+		}
+		// Charges of one merchant in different currencies are separate subscriptions,
+		// their amounts can't be compared.
+		key += "|" + strings.ToUpper(tx.Currency)
+		groups[key] = append(groups[key], tx)
+		names[key] = name
+	}
+
+	var proposals []Proposal
+	for key, charges := range groups {
+		proposal, ok := detectGroup(charges, statementEnd)
+		if !ok {
+			continue
+		}
+		proposal.ServiceName = names[key]
+		proposals = append(proposals, proposal)
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].Confidence != proposals[j].Confidence {
+			return proposals[i].Confidence > proposals[j].Confidence
+		}
+		return proposals[i].ServiceName < proposals[j].ServiceName
+	})
+	return proposals
+}
+
+func detectGroup(charges []Transaction, statementEnd time.Time) (Proposal, bool) {
+	sort.Slice(charges, func(i, j int) bool { return charges[i].Date.Before(charges[j].Date) })
+	charges = withinTolerance(charges)
+	if len(charges) < minAnnualCharges {
+		return Proposal{}, false
+	}
+
+	intervals := make([]float64, 0, len(charges)-1)
+	for i := 1; i < len(charges); i++ {
+		intervals = append(intervals, charges[i].Date.Sub(charges[i-1].Date).Hours()/24)
+	}
+	interval := median(intervals)
+	var cadence Cadence
+	for c, bounds := range cadenceDays {
+		if interval >= bounds[0] && interval <= bounds[1] {
+			cadence = c
+		}
+	}
+	if cadence == "" || (cadence == CadenceMonthly && len(charges) < minMonthlyCharges) {
+		return Proposal{}, false
+	}
+
+	regular := 0
+	bounds := cadenceDays[cadence]
+	for _, days := range intervals {
+		if days >= bounds[0] && days <= bounds[1] {
+			regular++
+		}
+	}
+	last := charges[len(charges)-1]
+	price := -last.Amount
+	if cadence == CadenceAnnual {
+		price /= 12
+	}
+	proposal := Proposal{
+		Price:          int(math.Round(float64(price) / 100)),
+		Currency:       last.Currency,
+		Cadence:        cadence,
+		StartDate:      domain.FromTime(charges[0].Date),
+		Charges:        len(charges),
+		LastChargeDate: last.Date.Format("2006-01-02"),
+		Confidence:     math.Round(float64(regular)/float64(len(intervals))*100) / 100,
+	}
+	if proposal.Price <= 0 {
+		return Proposal{}, false
+	}
+	if statementEnd.Sub(last.Date).Hours()/24 > bounds[1]*1.5 {
+		endDate := domain.FromTime(last.Date)
+		if cadence == CadenceAnnual {
+			endDate = domain.FromTime(last.Date.AddDate(0, 11, 0))
+		}
+		proposal.EndDate = &endDate
+	}
+	return proposal, true
+}
+
+// withinTolerance drops charges too far from the median amount, e.g. one-off purchases
+// from a merchant that also bills a subscription.
+func withinTolerance(charges []Transaction) []Transaction {
+	amounts := make([]float64, len(charges))
+	for i, tx := range charges {
+		amounts[i] = float64(-tx.Amount)
+	}
+	m := median(amounts)
+	kept := charges[:0]
+	for _, tx := range charges {
+		if math.Abs(float64(-tx.Amount)-m) <= m*amountTolerance {
+			kept = append(kept, tx)
+		}
+	}
+	return kept
+}
+
+// merchantKey normalizes a description into a grouping key and a readable name:
+// digits, punctuation and noise words are removed and the first three words kept.
+func merchantKey(description string) (string, string) {
+	words := strings.FieldsFunc(description, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	var kept []string
+	for _, word := range words {
+		if len([]rune(word)) < 2 || noiseWords[strings.ToLower(word)] {
+			continue
+		}
+		kept = append(kept, word)
+		if len(kept) == 3 {
+			break
+		}
+	}
+	if len(kept) == 0 {
+		return "", ""
+	}
+	return strings.ToLower(strings.Join(kept, " ")), strings.Join(kept, " ")
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
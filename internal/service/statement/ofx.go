@@ -0,0 +1,88 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/money"
+)
+
+// ParseOFX reads STMTTRN records from OFX 1.x (SGML, unclosed leaf tags) and OFX 2.x (XML).
+func ParseOFX(r io.Reader) ([]Transaction, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	currency := ofxValue(data, "CURDEF")
+
+	var transactions []Transaction
+	upper := asciiUpper(data)
+	for {
+		start := bytes.Index(upper, []byte("<STMTTRN>"))
+		if start < 0 {
+			break
+		}
+		end := bytes.Index(upper[start:], []byte("</STMTTRN>"))
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated STMTTRN", ErrInvalidStatement)
+		}
+		block := data[start : start+end]
+		data, upper = data[start+end:], upper[start+end:]
+
+		posted := ofxValue(block, "DTPOSTED")
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("%w: incorrect DTPOSTED %q", ErrInvalidStatement, posted)
+		}
+		date, err := parseDate(posted[:8])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		amount, err := money.ParseMinor(ofxValue(block, "TRNAMT"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+		}
+		description := ofxValue(block, "NAME")
+		if memo := ofxValue(block, "MEMO"); description == "" {
+			description = memo
+		}
+		transactions = append(transactions, Transaction{
+			Date:        date,
+			Amount:      amount,
+			Currency:    currency,
+			Description: description,
+		})
+	}
+	return transactions, nil
+}
+
+// ofxValue returns the text after <TAG> up to the next tag or line break.
+func ofxValue(data []byte, tag string) string {
+	open := []byte("<" + tag + ">")
+	i := bytes.Index(asciiUpper(data), open)
+	if i < 0 {
+		return ""
+	}
+	rest := data[i+len(open):]
+	if end := bytes.IndexAny(rest, "<\r\n"); end >= 0 {
+		rest = rest[:end]
+	}
+	return strings.TrimSpace(unescapeOFX(string(rest)))
+}
+
+func unescapeOFX(s string) string {
+	return strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'").Replace(s)
+}
+
+// asciiUpper upper-cases ASCII letters only, keeping byte offsets of data intact.
+func asciiUpper(data []byte) []byte {
+	upper := make([]byte, len(data))
+	for i, b := range data {
+		if 'a' <= b && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		upper[i] = b
+	}
+	return upper
+}
@@ -0,0 +1,61 @@
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatCSV     = "csv"
+	FormatOFX     = "ofx"
+	FormatCAMT053 = "camt053"
+)
+
+const maxStatementSize = 20 << 20
+
+// Parse reads a statement in the given format, or detects it from the content when
+// format is empty.
+func Parse(r io.Reader, format string, csvOpts CSVOptions) ([]Transaction, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxStatementSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxStatementSize {
+		return nil, fmt.Errorf("%w: statement is larger than %d bytes", ErrInvalidStatement, maxStatementSize)
+	}
+	if format == "" {
+		format = detectFormat(data)
+	}
+	switch format {
+	case FormatCSV:
+		return ParseCSV(bytes.NewReader(data), csvOpts)
+	case FormatOFX:
+		return ParseOFX(bytes.NewReader(data))
+	case FormatCAMT053:
+		return ParseCAMT053(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidStatement, format)
+	}
+}
+
+func detectFormat(data []byte) string {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	upper := asciiUpper(head)
+	switch {
+	case bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>")):
+		return FormatOFX
+	case bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt")):
+		return FormatCAMT053
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(head))
+		if scanner.Scan() && bytes.HasPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("<")) {
+			return FormatCAMT053
+		}
+		return FormatCSV
+	}
+}
@@ -0,0 +1,81 @@
+package statement
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+type Service struct {
+	log                 *slog.Logger
+	subscriptionService domain.SubscriptionInteractor
+}
+
+func NewService(log *slog.Logger, subscriptionService domain.SubscriptionInteractor) *Service {
+	return &Service{log: log, subscriptionService: subscriptionService}
+}
+
+// Analyze parses a statement and proposes subscriptions for its recurring charges,
+// flagging the ones the user already tracks under the same service name.
+func (s *Service) Analyze(ctx context.Context, userID uuid.UUID, r io.Reader, format string, csvOpts CSVOptions) ([]Proposal, error) {
+	const op = "service.statement.analyze"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+		slog.String("format", format),
+	)
+	log.Info("analyzing statement")
+	transactions, err := Parse(r, format, csvOpts)
+	if err != nil {
+		log.Warn("failed to parse statement", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	proposals := Detect(transactions)
+
+	tracked := make(map[string]bool)
+	err = s.subscriptionService.ExportSubscriptions(ctx, domain.SubscriptionFilter{UserID: &userID}, func(subscription *domain.Subscription) error {
+		tracked[strings.ToLower(subscription.ServiceName)] = true
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to get user subscriptions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range proposals {
+		proposals[i].AlreadyTracked = tracked[strings.ToLower(proposals[i].ServiceName)]
+	}
+	log.Info("statement analyzed", slog.Int("transactions", len(transactions)), slog.Int("proposals", len(proposals)))
+	return proposals, nil
+}
+
+// Confirm creates the proposals accepted by the user in one transaction.
+func (s *Service) Confirm(ctx context.Context, userID uuid.UUID, proposals []Proposal) ([]domain.BulkResult, error) {
+	const op = "service.statement.confirm"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("user_id", userID.String()),
+	)
+	subscriptions := make([]*domain.Subscription, len(proposals))
+	for i, proposal := range proposals {
+		subscriptions[i] = &domain.Subscription{
+			ServiceName: proposal.ServiceName,
+			Price:       proposal.Price,
+			UserID:      userID,
+			StartDate:   proposal.StartDate,
+			EndDate:     proposal.EndDate,
+		}
+	}
+	results, err := s.subscriptionService.BulkCreate(ctx, subscriptions, domain.BulkModeAtomic)
+	if err != nil {
+		log.Warn("failed to confirm proposals", sl.Err(err))
+		return results, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("proposals confirmed", slog.Int("created", len(results)))
+	return results, nil
+}
@@ -0,0 +1,44 @@
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidStatement = errors.New("invalid statement")
+
+// Transaction is a booked statement line. Amount is in minor units (kopecks, cents),
+// debits are negative.
+type Transaction struct {
+	Date        time.Time
+	Amount      int64
+	Currency    string
+	Description string
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"02.01.2006",
+	"02.01.2006 15:04:05",
+	"02/01/2006",
+	"20060102",
+}
+
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if len(s) >= 10 {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("incorrect date %q", s)
+}
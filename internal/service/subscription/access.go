@@ -0,0 +1,25 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// scopeUser narrows a user filter to the caller. A caller limited to one user gets its own ID
+// when no user is requested and ErrForbidden when another one is.
+func scopeUser(ctx context.Context, scope domain.Scope, userID *uuid.UUID) (*uuid.UUID, error) {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok || principal.AllUsers(scope) {
+		return userID, nil
+	}
+	if !principal.HasScope(scope) {
+		return nil, fmt.Errorf("%w: no %s permission", domain.ErrForbidden, scope)
+	}
+	if userID != nil && *userID != principal.UserID {
+		return nil, fmt.Errorf("%w: subscriptions of another user", domain.ErrForbidden)
+	}
+	return &principal.UserID, nil
+}
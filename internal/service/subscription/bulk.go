@@ -0,0 +1,202 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+)
+
+func (si *SubscriptionInteractor) BulkCreate(ctx context.Context, subscriptions []*domain.Subscription, mode domain.BulkMode) ([]domain.BulkResult, error) {
+	const op = "service.subscription.bulkCreate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("mode", string(mode)),
+		slog.Int("items", len(subscriptions)),
+	)
+	log.Info("creating subscriptions")
+	results := newBulkResults(len(subscriptions))
+	for i, subscription := range subscriptions {
+		subscription.Version = 1
+		results[i].Err = subscription.Validate()
+		if results[i].Err == nil {
+			results[i].Err = domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID)
+		}
+	}
+
+	if mode == domain.BulkModeAtomic {
+		if hasBulkErrors(results) {
+			log.Warn("invalid items, nothing created")
+			return abortBulk(results), fmt.Errorf("%s: %w", op, domain.ErrBulkAborted)
+		}
+		err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := si.subsRepo.SaveSubscriptions(ctx, subscriptions); err != nil {
+				return err
+			}
+			return si.recordChanges(ctx, domain.AuditCreate, created(subscriptions...)...)
+		})
+		if err != nil {
+			log.Error("failed to save subscriptions", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		for i, subscription := range subscriptions {
+			results[i].ID = subscription.ID
+			results[i].Version = subscription.Version
+		}
+		log.Info("subscriptions created")
+		return results, nil
+	}
+
+	for i, subscription := range subscriptions {
+		if results[i].Err != nil {
+			continue
+		}
+		err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			if _, err := si.subsRepo.SaveSubscription(ctx, subscription); err != nil {
+				return err
+			}
+			return si.recordChanges(ctx, domain.AuditCreate, created(subscription)...)
+		})
+		if err != nil {
+			log.Error("failed to save subscription", slog.Int("index", i), sl.Err(err))
+			results[i].Err = err
+			continue
+		}
+		results[i].ID = subscription.ID
+		results[i].Version = subscription.Version
+	}
+	log.Info("subscriptions processed")
+	return results, nil
+}
+
+func (si *SubscriptionInteractor) BulkUpdate(ctx context.Context, subscriptions []*domain.Subscription, mode domain.BulkMode) ([]domain.BulkResult, error) {
+	const op = "service.subscription.bulkUpdate"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("mode", string(mode)),
+		slog.Int("items", len(subscriptions)),
+	)
+	log.Info("updating subscriptions")
+	results := newBulkResults(len(subscriptions))
+	for i, subscription := range subscriptions {
+		results[i].ID = subscription.ID
+		results[i].Err = subscription.Validate()
+		if results[i].Err == nil {
+			results[i].Err = domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID)
+		}
+	}
+	err := si.applyBulk(ctx, results, mode, func(ctx context.Context, i int) error {
+		if err := si.update(ctx, subscriptions[i], domain.AuditUpdate); err != nil {
+			return err
+		}
+		results[i].Version = subscriptions[i].Version
+		return nil
+	})
+	if err != nil {
+		log.Warn("bulk update failed", sl.Err(err))
+		return results, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscriptions processed")
+	return results, nil
+}
+
+func (si *SubscriptionInteractor) BulkDelete(ctx context.Context, refs []domain.SubscriptionRef, mode domain.BulkMode) ([]domain.BulkResult, error) {
+	const op = "service.subscription.bulkDelete"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("mode", string(mode)),
+		slog.Int("items", len(refs)),
+	)
+	log.Info("deleting subscriptions")
+	results := newBulkResults(len(refs))
+	for i, ref := range refs {
+		results[i].ID = ref.ID
+	}
+	err := si.applyBulk(ctx, results, mode, func(ctx context.Context, i int) error {
+		return si.delete(ctx, refs[i].ID, refs[i].Version)
+	})
+	if err != nil {
+		log.Warn("bulk delete failed", sl.Err(err))
+		return results, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscriptions processed")
+	return results, nil
+}
+
+// applyBulk runs apply for every item without a validation error, each item in its own
+// transaction. In atomic mode all items share one transaction and the first failure
+// rolls back the whole batch and ErrBulkAborted is returned.
+func (si *SubscriptionInteractor) applyBulk(ctx context.Context, results []domain.BulkResult, mode domain.BulkMode, apply func(ctx context.Context, i int) error) error {
+	if mode != domain.BulkModeAtomic {
+		for i := range results {
+			if results[i].Err != nil {
+				continue
+			}
+			results[i].Err = si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+				return apply(ctx, i)
+			})
+		}
+		return nil
+	}
+
+	if hasBulkErrors(results) {
+		abortBulk(results)
+		return domain.ErrBulkAborted
+	}
+	var itemErr error
+	err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for i := range results {
+			if err := apply(ctx, i); err != nil {
+				results[i].Err = err
+				itemErr = err
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	abortBulk(results)
+	if itemErr != nil {
+		return domain.ErrBulkAborted
+	}
+	return err
+}
+
+func newBulkResults(n int) []domain.BulkResult {
+	results := make([]domain.BulkResult, n)
+	for i := range results {
+		results[i].Index = i
+	}
+	return results
+}
+
+func hasBulkErrors(results []domain.BulkResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// abortBulk marks items that did not fail on their own as rolled back.
+func abortBulk(results []domain.BulkResult) []domain.BulkResult {
+	for i := range results {
+		results[i].Version = 0
+		if results[i].Err == nil {
+			results[i].Err = domain.ErrBulkAborted
+		}
+	}
+	return results
+}
@@ -0,0 +1,160 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+)
+
+const eventBatchSize = 100
+
+// change is a stored mutation of one subscription: before is nil for a creation and
+// after is nil for a purge.
+type change struct {
+	before *domain.Subscription
+	after  *domain.Subscription
+}
+
+// operationEvents maps audited operations to the events they publish. A purge
+// publishes nothing, the deletion was published already.
+var operationEvents = map[domain.AuditOperation]domain.EventType{
+	domain.AuditCreate:  domain.EventSubscriptionCreated,
+	domain.AuditUpdate:  domain.EventSubscriptionUpdated,
+	domain.AuditPatch:   domain.EventSubscriptionUpdated,
+	domain.AuditDelete:  domain.EventSubscriptionDeleted,
+	domain.AuditRestore: domain.EventSubscriptionRestored,
+}
+
+// recordChanges appends the events and audit entries of changes. It is called within
+// the transaction of the mutation, so both are stored only if it commits.
+func (si *SubscriptionInteractor) recordChanges(ctx context.Context, operation domain.AuditOperation, changes ...change) error {
+	now := time.Now()
+	actor := domain.ActorFromContext(ctx)
+	requestID := domain.RequestIDFromContext(ctx)
+	eventType, publish := operationEvents[operation]
+	events := make([]domain.Event, 0, len(changes))
+	entries := make([]domain.AuditEntry, len(changes))
+	for i, c := range changes {
+		subscription := c.after
+		if subscription == nil {
+			subscription = c.before
+		}
+		if publish {
+			events = append(events, domain.NewSubscriptionEvent(eventType, subscription, now))
+		}
+		entries[i] = domain.AuditEntry{
+			SubscriptionID: subscription.ID,
+			Operation:      operation,
+			Actor:          actor,
+			RequestID:      requestID,
+			Before:         c.before,
+			After:          c.after,
+		}
+	}
+	if err := si.outbox.Append(ctx, events...); err != nil {
+		return err
+	}
+	return si.audit.AppendAudit(ctx, entries...)
+}
+
+// mutate applies a change to a stored subscription of the caller and records it in one transaction.
+// The row is loaded first for the audit snapshot and a change without a version is
+// pinned to the loaded one, so the snapshot is exactly the row that gets overwritten.
+func (si *SubscriptionInteractor) mutate(ctx context.Context, subscriptionID uuid.UUID, version int, operation domain.AuditOperation, apply func(ctx context.Context, version int) (*domain.Subscription, error)) (*domain.Subscription, error) {
+	var after *domain.Subscription
+	err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		before, err := si.subsRepo.Subscription(ctx, subscriptionID, operation == domain.AuditRestore)
+		if err != nil {
+			return err
+		}
+		if err := domain.Authorize(ctx, domain.ScopeWrite, before.UserID); err != nil {
+			return err
+		}
+		if version == domain.AnyVersion {
+			version = before.Version
+		}
+		after, err = apply(ctx, version)
+		if err != nil {
+			return err
+		}
+		return si.recordChanges(ctx, operation, change{before: before, after: after})
+	})
+	return after, err
+}
+
+// update stores subscription and refreshes it with the stored row.
+func (si *SubscriptionInteractor) update(ctx context.Context, subscription *domain.Subscription, operation domain.AuditOperation) error {
+	_, err := si.mutate(ctx, subscription.ID, subscription.Version, operation, func(ctx context.Context, version int) (*domain.Subscription, error) {
+		subscription.Version = version
+		return subscription, si.subsRepo.UpdateSubscription(ctx, subscription)
+	})
+	return err
+}
+
+func (si *SubscriptionInteractor) delete(ctx context.Context, subscriptionID uuid.UUID, version int) error {
+	_, err := si.mutate(ctx, subscriptionID, version, domain.AuditDelete, func(ctx context.Context, version int) (*domain.Subscription, error) {
+		return si.subsRepo.DeleteSubscription(ctx, subscriptionID, version)
+	})
+	return err
+}
+
+func created(subscriptions ...*domain.Subscription) []change {
+	changes := make([]change, len(subscriptions))
+	for i, subscription := range subscriptions {
+		changes[i].after = subscription
+	}
+	return changes
+}
+
+// PublishEndedSubscriptions records EventSubscriptionEnded for every subscription
+// whose last paid month is month. Event IDs are stable, so a repeated call for the
+// same month records nothing new while the first events are kept in the outbox.
+func (si *SubscriptionInteractor) PublishEndedSubscriptions(ctx context.Context, month domain.MonthYear) (int, error) {
+	const op = "service.subscription.publishEnded"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("month", month.String()),
+	)
+	published := 0
+	var batch []domain.Event
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := si.outbox.Append(ctx, batch...); err != nil {
+			return err
+		}
+		published += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	now := time.Now()
+	filter := domain.SubscriptionFilter{ActiveFrom: &month, ActiveTo: &month}
+	err := si.subsRepo.StreamSubscriptions(ctx, filter, func(subscription *domain.Subscription) error {
+		if subscription.EndDate == nil || domain.CompareMonthYears(*subscription.EndDate, month) != 0 {
+			return nil
+		}
+		batch = append(batch, domain.NewSubscriptionEvent(domain.EventSubscriptionEnded, subscription, now))
+		if len(batch) < eventBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		log.Error("failed to publish ended subscriptions", sl.Err(err))
+		return published, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("ended subscriptions published", slog.Int("events", published))
+	return published, nil
+}
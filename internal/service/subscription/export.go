@@ -0,0 +1,126 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+)
+
+func (si *SubscriptionInteractor) ExportSubscriptions(ctx context.Context, filter domain.SubscriptionFilter, fn func(*domain.Subscription) error) error {
+	const op = "service.subscription.export"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+	)
+	log.Info("exporting subscriptions")
+	userID, err := scopeUser(ctx, domain.ScopeRead, filter.UserID)
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	filter.UserID = userID
+	exported := 0
+	err = si.subsRepo.StreamSubscriptions(ctx, filter, func(subscription *domain.Subscription) error {
+		exported++
+		return fn(subscription)
+	})
+	if err != nil {
+		log.Error("failed to export subscriptions", sl.Err(err), slog.Int("exported", exported))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscriptions exported", slog.Int("exported", exported))
+	return nil
+}
+
+func (si *SubscriptionInteractor) CostBreakdown(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate domain.MonthYear, groupBy domain.CostGroup, includeDeleted bool) ([]domain.CostBreakdownLine, error) {
+	const op = "service.subscription.costBreakdown"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("start_date", startDate.String()),
+		slog.String("end_date", endDate.String()),
+		slog.String("group_by", string(groupBy)),
+	)
+	if endDate.IsBefore(startDate) {
+		log.Error("start date cannot be after end date")
+		return nil, errors.New("start date cannot be after end date")
+	}
+	userID, err := scopeUser(ctx, domain.ScopeReports, userID)
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lines := make(map[string]*domain.CostBreakdownLine)
+	line := func(group string) *domain.CostBreakdownLine {
+		l, ok := lines[group]
+		if !ok {
+			l = &domain.CostBreakdownLine{Group: group}
+			lines[group] = l
+		}
+		return l
+	}
+	filter := domain.SubscriptionFilter{
+		UserID:         userID,
+		ServiceName:    serviceName,
+		ActiveFrom:     &startDate,
+		ActiveTo:       &endDate,
+		IncludeDeleted: includeDeleted,
+	}
+	err = si.subsRepo.StreamSubscriptions(ctx, filter, func(sub *domain.Subscription) error {
+		months := si.calculateActiveMonths(sub.StartDate, sub.EndDate, startDate, endDate)
+		if months == 0 {
+			return nil
+		}
+		switch groupBy {
+		case domain.CostGroupUserID:
+			l := line(sub.UserID.String())
+			l.Subscriptions++
+			l.ActiveMonths += months
+			l.Total += sub.Price * months
+		case domain.CostGroupMonth:
+			first := domain.MaxMonthYear(sub.StartDate, startDate)
+			for i := 0; i < months; i++ {
+				month := domain.FromTime(first.ToTime().AddDate(0, i, 0))
+				l := line(month.String())
+				l.Subscriptions++
+				l.ActiveMonths++
+				l.Total += sub.Price
+			}
+		default:
+			l := line(sub.ServiceName)
+			l.Subscriptions++
+			l.ActiveMonths += months
+			l.Total += sub.Price * months
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("failed to get subscriptions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	breakdown := make([]domain.CostBreakdownLine, 0, len(lines))
+	for _, l := range lines {
+		breakdown = append(breakdown, *l)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if groupBy == domain.CostGroupMonth {
+			a, _ := domain.ParseMonthYear(breakdown[i].Group)
+			b, _ := domain.ParseMonthYear(breakdown[j].Group)
+			return a.IsBefore(b)
+		}
+		return breakdown[i].Group < breakdown[j].Group
+	})
+	log.Info("cost breakdown provided", slog.Int("groups", len(breakdown)))
+	return breakdown, nil
+}
@@ -5,24 +5,33 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
 )
 
+const purgeBatchSize = 100
+
 type SubscriptionInteractor struct {
 	log      *slog.Logger
 	subsRepo domain.SubscriptionRepository
+	tx       domain.Transactor
+	outbox   domain.OutboxRepository
+	audit    domain.AuditRepository
 }
 
-func NewSubscriptionInteractor(log *slog.Logger, subsRepo domain.SubscriptionRepository) *SubscriptionInteractor {
-	return &SubscriptionInteractor{log: log, subsRepo: subsRepo}
+func NewSubscriptionInteractor(log *slog.Logger, subsRepo domain.SubscriptionRepository, tx domain.Transactor, outbox domain.OutboxRepository, audit domain.AuditRepository) *SubscriptionInteractor {
+	return &SubscriptionInteractor{log: log, subsRepo: subsRepo, tx: tx, outbox: outbox, audit: audit}
 }
 
-func (si *SubscriptionInteractor) AddSubscription(ctx context.Context, serviceName string, price int, userID uuid.UUID, startDate domain.MonthYear, endDate *domain.MonthYear) (uuid.UUID, error) {
+func (si *SubscriptionInteractor) AddSubscription(ctx context.Context, serviceName string, price int, userID uuid.UUID, startDate domain.MonthYear, endDate *domain.MonthYear) (*domain.Subscription, error) {
 	const op = "service.subscription.add"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 		slog.String("service_name", serviceName),
 		slog.String("userID", userID.String()),
@@ -34,25 +43,44 @@ func (si *SubscriptionInteractor) AddSubscription(ctx context.Context, serviceNa
 		UserID:      userID,
 		StartDate:   startDate,
 		EndDate:     endDate,
+		Version:     1,
+	}
+	if err := subscription.Validate(); err != nil {
+		log.Warn("invalid subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := domain.Authorize(ctx, domain.ScopeWrite, userID); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := si.subsRepo.SaveSubscription(ctx, subscription)
+	err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := si.subsRepo.SaveSubscription(ctx, subscription); err != nil {
+			return err
+		}
+		return si.recordChanges(ctx, domain.AuditCreate, created(subscription)...)
+	})
 	if err != nil {
 		log.Error("failed to save subscription", sl.Err(err))
-		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	log.Info("Subscription saved!")
-	return id, nil
+	return subscription, nil
 }
 
 func (si *SubscriptionInteractor) Subscription(ctx context.Context, subscriptionID uuid.UUID) (*domain.Subscription, error) {
 	const op = "service.subscription.get"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 		slog.String("id", subscriptionID.String()),
 	)
 	log.Info("getting subscription")
-	subscription, err := si.subsRepo.Subscription(ctx, subscriptionID)
+	subscription, err := si.subsRepo.Subscription(ctx, subscriptionID, false)
+	if err == nil {
+		err = domain.Authorize(ctx, domain.ScopeRead, subscription.UserID)
+	}
 	if err != nil {
 		log.Error("failed to get subscription", sl.Err(err))
 		return nil, fmt.Errorf("%s: %w", op, err)
@@ -61,14 +89,16 @@ func (si *SubscriptionInteractor) Subscription(ctx context.Context, subscription
 	return subscription, nil
 }
 
-func (si *SubscriptionInteractor) DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID) error {
+func (si *SubscriptionInteractor) DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) error {
 	const op = "service.subscription.delete"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 		slog.String("id", subscriptionID.String()),
 	)
 	log.Info("deleting subscription")
-	if err := si.subsRepo.DeleteSubscription(ctx, subscriptionID); err != nil {
+	if err := si.delete(ctx, subscriptionID, version); err != nil {
 		log.Error("failed to delete subscription", sl.Err(err))
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -76,9 +106,72 @@ func (si *SubscriptionInteractor) DeleteSubscription(ctx context.Context, subscr
 	return nil
 }
 
-func (si *SubscriptionInteractor) UpdateSubscription(ctx context.Context, subscriptionID uuid.UUID, serviceName string, price int, userID uuid.UUID, startDate domain.MonthYear, endDate *domain.MonthYear) error {
+func (si *SubscriptionInteractor) RestoreSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*domain.Subscription, error) {
+	const op = "service.subscription.restore"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("id", subscriptionID.String()),
+	)
+	log.Info("restoring subscription")
+	restored, err := si.mutate(ctx, subscriptionID, version, domain.AuditRestore, func(ctx context.Context, version int) (*domain.Subscription, error) {
+		return si.subsRepo.RestoreSubscription(ctx, subscriptionID, version)
+	})
+	if err != nil {
+		log.Error("failed to restore subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscription restored")
+	return restored, nil
+}
+
+// PurgeDeleted removes subscriptions soft-deleted more than retention ago, a batch per
+// transaction, and returns how many were removed.
+func (si *SubscriptionInteractor) PurgeDeleted(ctx context.Context, retention time.Duration) (int, error) {
+	const op = "service.subscription.purgeDeleted"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.Duration("retention", retention),
+	)
+	before := time.Now().Add(-retention)
+	purged := 0
+	for {
+		var batch []*domain.Subscription
+		err := si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			batch, err = si.subsRepo.PurgeDeleted(ctx, before, purgeBatchSize)
+			if err != nil || len(batch) == 0 {
+				return err
+			}
+			changes := make([]change, len(batch))
+			for i, subscription := range batch {
+				changes[i].before = subscription
+			}
+			return si.recordChanges(ctx, domain.AuditPurge, changes...)
+		})
+		if err != nil {
+			log.Error("failed to purge deleted subscriptions", sl.Err(err))
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+		purged += len(batch)
+		if len(batch) < purgeBatchSize {
+			break
+		}
+	}
+	if purged > 0 {
+		log.Info("deleted subscriptions purged", slog.Int("purged", purged))
+	}
+	return purged, nil
+}
+
+func (si *SubscriptionInteractor) UpdateSubscription(ctx context.Context, subscriptionID uuid.UUID, serviceName string, price int, userID uuid.UUID, startDate domain.MonthYear, endDate *domain.MonthYear, version int) (*domain.Subscription, error) {
 	const op = "service.subscription.update"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 		slog.String("subscription_id", subscriptionID.String()),
 		slog.String("service_name", serviceName),
@@ -92,26 +185,87 @@ func (si *SubscriptionInteractor) UpdateSubscription(ctx context.Context, subscr
 		UserID:      userID,
 		StartDate:   startDate,
 		EndDate:     endDate,
+		Version:     version,
 	}
-	if err := si.subsRepo.UpdateSubscription(ctx, subscription); err != nil {
-		log.Error("failed to update subscription")
-		return fmt.Errorf("%s: %w", op, err)
+	if err := subscription.Validate(); err != nil {
+		log.Warn("invalid subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return nil
+	if err := domain.Authorize(ctx, domain.ScopeWrite, userID); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := si.update(ctx, subscription, domain.AuditUpdate); err != nil {
+		log.Error("failed to update subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscription updated")
+	return subscription, nil
+}
+
+func (si *SubscriptionInteractor) PatchSubscription(ctx context.Context, subscriptionID uuid.UUID, patch domain.SubscriptionPatch, version int) (*domain.Subscription, error) {
+	const op = "service.subscription.patch"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("subscription_id", subscriptionID.String()),
+	)
+	log.Info("patching subscription")
+	subscription, err := si.subsRepo.Subscription(ctx, subscriptionID, false)
+	if err == nil {
+		err = domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID)
+	}
+	if err != nil {
+		log.Error("failed to get subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	before := *subscription
+	patch.Apply(subscription)
+	if version != domain.AnyVersion {
+		subscription.Version = version
+	}
+	if err := subscription.Validate(); err != nil {
+		log.Warn("invalid subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := domain.Authorize(ctx, domain.ScopeWrite, subscription.UserID); err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	err = si.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := si.subsRepo.UpdateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+		return si.recordChanges(ctx, domain.AuditPatch, change{before: &before, after: subscription})
+	})
+	if err != nil {
+		log.Error("failed to update subscription", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("subscription patched")
+	return subscription, nil
 }
 
-func (si *SubscriptionInteractor) ListSubscription(ctx context.Context, offset, limit int) ([]*domain.Subscription, int64, error) {
+func (si *SubscriptionInteractor) ListSubscription(ctx context.Context, offset, limit int, includeDeleted bool) ([]*domain.Subscription, int64, error) {
 	const op = "service.subscription.list"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 	)
 	log.Info("getting list of subscriptions")
-	list, err := si.subsRepo.ListSubscription(ctx, offset, limit)
+	userID, err := scopeUser(ctx, domain.ScopeRead, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	filter := domain.SubscriptionFilter{UserID: userID, IncludeDeleted: includeDeleted}
+	list, err := si.subsRepo.ListSubscription(ctx, filter, offset, limit)
 	if err != nil {
 		log.Error("failed to get list of subscription")
 		return nil, 0, fmt.Errorf("%s: %w", op, err)
 	}
-	total, err := si.subsRepo.Count(ctx)
+	total, err := si.subsRepo.Count(ctx, filter)
 	if err != nil {
 		log.Error("failed to count of subscription")
 		return list, 0, err
@@ -121,9 +275,51 @@ func (si *SubscriptionInteractor) ListSubscription(ctx context.Context, offset,
 	return list, total, nil
 }
 
-func (si *SubscriptionInteractor) TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate domain.MonthYear) (int, error) {
+func (si *SubscriptionInteractor) History(ctx context.Context, subscriptionID uuid.UUID, offset, limit int) ([]domain.AuditEntry, int64, error) {
+	const op = "service.subscription.history"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
+		slog.String("op", op),
+		slog.String("subscription_id", subscriptionID.String()),
+	)
+	log.Info("getting subscription history")
+	// The audit log is filtered by tenant in the repository, callers limited to their
+	// own subscriptions also need the subscription to know whose it is.
+	principal, ok := domain.PrincipalFromContext(ctx)
+	scoped := ok && !principal.AllUsers(domain.ScopeRead)
+	if scoped {
+		subscription, err := si.subsRepo.Subscription(ctx, subscriptionID, true)
+		if err == nil {
+			err = domain.Authorize(ctx, domain.ScopeRead, subscription.UserID)
+		}
+		if err != nil {
+			log.Warn("failed to authorize history", sl.Err(err))
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	entries, total, err := si.audit.History(ctx, subscriptionID, offset, limit)
+	if err != nil {
+		log.Error("failed to get subscription history", sl.Err(err))
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	// Without entries the subscription may not exist at all, which other callers learned
+	// from loading it above. History of purged subscriptions stays available to them.
+	if total == 0 && !scoped {
+		if _, err := si.subsRepo.Subscription(ctx, subscriptionID, true); err != nil {
+			log.Warn("failed to get subscription", sl.Err(err))
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	log.Info("history provided")
+	return entries, total, nil
+}
+
+func (si *SubscriptionInteractor) TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate domain.MonthYear, includeDeleted bool) (int, error) {
 	const op = "service.subscription.totalCost"
-	log := si.log.With(
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.LoggerFromContext(ctx, si.log).With(
 		slog.String("op", op),
 		slog.String("start_date", startDate.String()),
 		slog.String("end_date", endDate.String()),
@@ -132,8 +328,13 @@ func (si *SubscriptionInteractor) TotalCost(ctx context.Context, userID *uuid.UU
 		log.Error("start date cannot be after end date")
 		return 0, errors.New("start date cannot be after end date")
 	}
+	userID, err := scopeUser(ctx, domain.ScopeReports, userID)
+	if err != nil {
+		log.Warn("access denied", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-	subscriptions, err := si.subsRepo.TotalCost(ctx, userID, serviceName, startDate, endDate)
+	subscriptions, err := si.subsRepo.TotalCost(ctx, userID, serviceName, startDate, endDate, includeDeleted)
 	if err != nil {
 		log.Error("failed to get suscriptions", sl.Err(err))
 		return 0, err
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+var ErrInvalidEndpoint = errors.New("invalid webhook endpoint")
+
+type Service struct {
+	log  *slog.Logger
+	repo domain.WebhookRepository
+}
+
+func NewService(log *slog.Logger, repo domain.WebhookRepository) *Service {
+	return &Service{log: log, repo: repo}
+}
+
+// RegisterEndpoint stores an endpoint with a generated signing secret. The returned
+// endpoint is the only place the secret is shown.
+func (s *Service) RegisterEndpoint(ctx context.Context, rawURL string, eventTypes []domain.EventType, userID *uuid.UUID) (*domain.WebhookEndpoint, error) {
+	const op = "service.webhook.register"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("url", rawURL),
+	)
+	if err := validateURL(rawURL); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", op, ErrInvalidEndpoint, err)
+	}
+	for _, eventType := range eventTypes {
+		if !eventType.Valid() {
+			return nil, fmt.Errorf("%s: %w: unknown event type %q", op, ErrInvalidEndpoint, eventType)
+		}
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	endpoint := &domain.WebhookEndpoint{
+		URL:        rawURL,
+		Secret:     "whsec_" + hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		UserID:     userID,
+		Active:     true,
+	}
+	if err := s.repo.CreateEndpoint(ctx, endpoint); err != nil {
+		log.Error("failed to register webhook endpoint", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("webhook endpoint registered", slog.String("endpoint_id", endpoint.ID.String()))
+	return endpoint, nil
+}
+
+// validateURL accepts absolute http(s) URLs with a host name. Credentials are refused,
+// they would be stored and listed in plain text.
+func validateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("url should be an absolute http(s) url")
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return errors.New("url should use http or https")
+	}
+	if parsed.Hostname() == "" {
+		return errors.New("url should have a host")
+	}
+	if parsed.User != nil {
+		return errors.New("url shouldn`t contain credentials")
+	}
+	return nil
+}
+
+func (s *Service) Endpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	const op = "service.webhook.list"
+	endpoints, err := s.repo.ListEndpoints(ctx)
+	if err != nil {
+		sl.LoggerFromContext(ctx, s.log).Error("failed to list webhook endpoints", slog.String("op", op), sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	for _, endpoint := range endpoints {
+		endpoint.Secret = ""
+	}
+	return endpoints, nil
+}
+
+func (s *Service) DeleteEndpoint(ctx context.Context, endpointID uuid.UUID) error {
+	const op = "service.webhook.delete"
+	log := sl.LoggerFromContext(ctx, s.log).With(
+		slog.String("op", op),
+		slog.String("endpoint_id", endpointID.String()),
+	)
+	if err := s.repo.DeleteEndpoint(ctx, endpointID); err != nil {
+		log.Error("failed to delete webhook endpoint", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("webhook endpoint deleted")
+	return nil
+}
+
+func (s *Service) Deliveries(ctx context.Context, endpointID uuid.UUID, offset, limit int) ([]*domain.WebhookDelivery, int64, error) {
+	const op = "service.webhook.deliveries"
+	if _, err := s.repo.Endpoint(ctx, endpointID); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	deliveries, total, err := s.repo.ListDeliveries(ctx, endpointID, offset, limit)
+	if err != nil {
+		sl.LoggerFromContext(ctx, s.log).Error("failed to list webhook deliveries", slog.String("op", op), sl.Err(err))
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, total, nil
+}
+
+// Publish schedules a delivery of every event to the endpoints subscribed to it.
+func (s *Service) Publish(ctx context.Context, events ...domain.Event) error {
+	const op = "service.webhook.publish"
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := s.repo.Enqueue(ctx, event, payload); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const testSecret = "whsec_test"
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	body := []byte(`{"type":"subscription.created"}`)
+	received := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		received <- Verify(testSecret, r.Header.Get(SignatureHeader), payload, 5*time.Minute, time.Now())
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	header := Sign(testSecret, time.Now(), body)
+	if !regexp.MustCompile(`^t=\d+,v1=[0-9a-f]{64}$`).MatchString(header) {
+		t.Fatalf("Sign() = %q, want t=<unix>,v1=<hex>", header)
+	}
+	req.Header.Set(SignatureHeader, header)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	resp.Body.Close()
+	if err := <-received; err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":"1"}`)
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		wantErr bool
+	}{
+		{name: "valid", header: Sign(testSecret, now, body), body: body},
+		{name: "within tolerance", header: Sign(testSecret, now.Add(-4*time.Minute), body), body: body},
+		{name: "clock skew ahead", header: Sign(testSecret, now.Add(4*time.Minute), body), body: body},
+		{name: "stale timestamp", header: Sign(testSecret, now.Add(-6*time.Minute), body), body: body, wantErr: true},
+		{name: "timestamp from the future", header: Sign(testSecret, now.Add(6*time.Minute), body), body: body, wantErr: true},
+		{name: "tampered body", header: Sign(testSecret, now, body), body: []byte(`{"id":"2"}`), wantErr: true},
+		{name: "other secret", header: Sign("whsec_other", now, body), body: body, wantErr: true},
+		{name: "no signature", header: "t=1700000000", body: body, wantErr: true},
+		{name: "no timestamp", header: "v1=abc", body: body, wantErr: true},
+		{name: "empty", header: "", body: body, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(testSecret, tt.header, tt.body, 5*time.Minute, now)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/logger/sl"
+)
+
+const maxErrorBodySize = 1 << 10
+
+type WorkerConfig struct {
+	PollInterval time.Duration
+	Timeout      time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	// Retries wait BaseBackoff * 2^(attempt-1), capped at MaxBackoff, with up to 10% jitter.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+type Worker struct {
+	log    *slog.Logger
+	repo   domain.WebhookRepository
+	client *http.Client
+	cfg    WorkerConfig
+}
+
+func NewWorker(log *slog.Logger, repo domain.WebhookRepository, cfg WorkerConfig) *Worker {
+	return &Worker{
+		log:    log,
+		repo:   repo,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+	}
+}
+
+// Run delivers due webhooks until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		// A full batch means more deliveries are probably due, so poll again right away.
+		if w.deliverBatch(ctx) == w.cfg.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverBatch sends one batch of due deliveries concurrently and returns its size.
+func (w *Worker) deliverBatch(ctx context.Context) int {
+	const op = "service.webhook.deliver"
+	log := w.log.With(slog.String("op", op))
+	// The lease covers the slowest delivery of the batch, after it expires another
+	// worker may pick up a delivery whose outcome was not recorded.
+	deliveries, err := w.repo.ClaimDeliveries(ctx, w.cfg.BatchSize, 2*w.cfg.Timeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("failed to claim webhook deliveries", sl.Err(err))
+		}
+		return 0
+	}
+	endpoints := make(map[uuid.UUID]*domain.WebhookEndpoint)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = w.repo.Endpoint(ctx, delivery.EndpointID)
+			if err != nil {
+				log.Error("failed to get webhook endpoint", slog.String("endpoint_id", delivery.EndpointID.String()), sl.Err(err))
+				continue
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.attempt(ctx, log, endpoint, delivery)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+func (w *Worker) attempt(ctx context.Context, log *slog.Logger, endpoint *domain.WebhookEndpoint, delivery *domain.WebhookDelivery) {
+	log = log.With(
+		slog.Int64("delivery_id", delivery.ID),
+		slog.String("endpoint_id", endpoint.ID.String()),
+		slog.String("event_id", delivery.EventID),
+	)
+	statusCode, err := w.send(ctx, endpoint, delivery)
+	if ctx.Err() != nil {
+		// Shutting down: the lease expires and the delivery is retried later.
+		return
+	}
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = domain.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		log.Info("webhook delivered", slog.Int("attempts", delivery.Attempts))
+	case delivery.Attempts >= w.cfg.MaxAttempts:
+		delivery.Status = domain.DeliveryFailed
+		delivery.LastError = err.Error()
+		log.Warn("webhook delivery failed permanently", slog.Int("attempts", delivery.Attempts), sl.Err(err))
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(w.backoff(delivery.Attempts))
+		log.Info("webhook delivery failed, retrying", slog.Int("attempts", delivery.Attempts), slog.Time("next_attempt_at", delivery.NextAttemptAt), sl.Err(err))
+	}
+	if err := w.repo.RecordAttempt(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Error("failed to record webhook attempt", sl.Err(err))
+	}
+}
+
+func (w *Worker) send(ctx context.Context, endpoint *domain.WebhookEndpoint, delivery *domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "subscription-aggregator-webhooks/1.0")
+	req.Header.Set(EventIDHeader, delivery.EventID)
+	req.Header.Set(EventTypeHeader, string(delivery.EventType))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, time.Now(), body))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if len(snippet) == 0 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, snippet)
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.MaxBackoff
+	if shift := attempts - 1; shift < 32 {
+		if d := w.cfg.BaseBackoff << shift; d > 0 && d < delay {
+			delay = d
+		}
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/10+1))
+}
@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+)
+
+// memoryRepository keeps endpoints and deliveries in memory the way the Postgres
+// repository claims and records them.
+type memoryRepository struct {
+	mu         sync.Mutex
+	endpoints  map[uuid.UUID]*domain.WebhookEndpoint
+	deliveries map[int64]domain.WebhookDelivery
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		endpoints:  make(map[uuid.UUID]*domain.WebhookEndpoint),
+		deliveries: make(map[int64]domain.WebhookDelivery),
+	}
+}
+
+func (r *memoryRepository) CreateEndpoint(_ context.Context, endpoint *domain.WebhookEndpoint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	endpoint.ID = uuid.New()
+	r.endpoints[endpoint.ID] = endpoint
+	return nil
+}
+
+func (r *memoryRepository) Endpoint(_ context.Context, endpointID uuid.UUID) (*domain.WebhookEndpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.endpoints[endpointID], nil
+}
+
+func (r *memoryRepository) ListEndpoints(context.Context) ([]*domain.WebhookEndpoint, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) DeleteEndpoint(context.Context, uuid.UUID) error {
+	return nil
+}
+
+func (r *memoryRepository) Enqueue(context.Context, domain.Event, []byte) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) ClaimDeliveries(_ context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var claimed []*domain.WebhookDelivery
+	for id, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != domain.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		claimed = append(claimed, &delivery)
+		delivery.NextAttemptAt = now.Add(lease)
+		r.deliveries[id] = delivery
+	}
+	return claimed, nil
+}
+
+func (r *memoryRepository) RecordAttempt(_ context.Context, delivery *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryRepository) ListDeliveries(context.Context, uuid.UUID, int, int) ([]*domain.WebhookDelivery, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *memoryRepository) delivery(id int64) domain.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id]
+}
+
+// makeDue lets the next batch pick the delivery up without waiting for its backoff.
+func (r *memoryRepository) makeDue(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery := r.deliveries[id]
+	delivery.NextAttemptAt = time.Now().Add(-time.Second)
+	r.deliveries[id] = delivery
+}
+
+type workerFixture struct {
+	repo   *memoryRepository
+	worker *Worker
+}
+
+func newWorkerFixture(t *testing.T, receiver http.Handler, cfg WorkerConfig) *workerFixture {
+	t.Helper()
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+	repo := newMemoryRepository()
+	endpoint := &domain.WebhookEndpoint{URL: server.URL, Secret: testSecret, Active: true}
+	repo.CreateEndpoint(context.Background(), endpoint)
+	repo.deliveries[1] = domain.WebhookDelivery{
+		ID:            1,
+		EndpointID:    endpoint.ID,
+		EventID:       "subscription.created:1",
+		EventType:     domain.EventSubscriptionCreated,
+		Payload:       []byte(`{"id":"subscription.created:1"}`),
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: time.Now().Add(-time.Second),
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &workerFixture{repo: repo, worker: NewWorker(log, repo, cfg)}
+}
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		PollInterval: time.Hour,
+		Timeout:      time.Second,
+		BatchSize:    10,
+		MaxAttempts:  5,
+		BaseBackoff:  time.Minute,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+func TestWorkerDeliversSignedRequest(t *testing.T) {
+	var got http.Header
+	var body []byte
+	f := newWorkerFixture(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}), testWorkerConfig())
+
+	if n := f.worker.deliverBatch(context.Background()); n != 1 {
+		t.Fatalf("deliverBatch() = %d, want 1", n)
+	}
+	if err := Verify(testSecret, got.Get(SignatureHeader), body, time.Minute, time.Now()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if got.Get(EventIDHeader) != "subscription.created:1" || got.Get(EventTypeHeader) != string(domain.EventSubscriptionCreated) {
+		t.Errorf("event headers = %q, %q", got.Get(EventIDHeader), got.Get(EventTypeHeader))
+	}
+	delivery := f.repo.delivery(1)
+	if delivery.Status != domain.DeliverySucceeded || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, want succeeded after 1 attempt", delivery)
+	}
+	if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("LastStatusCode = %v, want 204", delivery.LastStatusCode)
+	}
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	f := newWorkerFixture(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), testWorkerConfig())
+	cfg := testWorkerConfig()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		f.worker.deliverBatch(context.Background())
+		delivery := f.repo.delivery(1)
+		if delivery.Status != domain.DeliveryPending || delivery.Attempts != attempt {
+			t.Fatalf("attempt %d: delivery = %+v, want pending", attempt, delivery)
+		}
+		if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusServiceUnavailable {
+			t.Errorf("attempt %d: LastStatusCode = %v, want 503", attempt, delivery.LastStatusCode)
+		}
+		if !strings.Contains(delivery.LastError, "temporarily unavailable") {
+			t.Errorf("attempt %d: LastError = %q", attempt, delivery.LastError)
+		}
+		backoff := cfg.BaseBackoff << (attempt - 1)
+		wait := delivery.NextAttemptAt.Sub(before)
+		if wait < backoff || wait > backoff+backoff/10+time.Second {
+			t.Errorf("attempt %d: next attempt in %v, want %v plus up to 10%% jitter", attempt, wait, backoff)
+		}
+		if n := f.worker.deliverBatch(context.Background()); n != 0 {
+			t.Fatalf("attempt %d: delivery retried before its backoff", attempt)
+		}
+		f.repo.makeDue(1)
+	}
+
+	f.worker.deliverBatch(context.Background())
+	if delivery := f.repo.delivery(1); delivery.Status != domain.DeliverySucceeded || delivery.Attempts != 3 {
+		t.Errorf("delivery = %+v, want succeeded on the third attempt", delivery)
+	}
+}
+
+func TestWorkerRetriesOnTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	cfg := testWorkerConfig()
+	cfg.Timeout = 50 * time.Millisecond
+	f := newWorkerFixture(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}), cfg)
+
+	f.worker.deliverBatch(context.Background())
+	delivery := f.repo.delivery(1)
+	if delivery.Status != domain.DeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("delivery = %+v, want pending after 1 attempt", delivery)
+	}
+	if delivery.LastStatusCode != nil {
+		t.Errorf("LastStatusCode = %d, want none", *delivery.LastStatusCode)
+	}
+	if !strings.Contains(delivery.LastError, "Timeout") {
+		t.Errorf("LastError = %q, want a timeout", delivery.LastError)
+	}
+	if !delivery.NextAttemptAt.After(time.Now().Add(cfg.BaseBackoff - time.Second)) {
+		t.Errorf("NextAttemptAt = %v, want after the base backoff", delivery.NextAttemptAt)
+	}
+}
+
+func TestWorkerFailsAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	cfg := testWorkerConfig()
+	cfg.MaxAttempts = 3
+	f := newWorkerFixture(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}), cfg)
+
+	for i := 0; i < cfg.MaxAttempts+2; i++ {
+		f.worker.deliverBatch(context.Background())
+		f.repo.makeDue(1)
+	}
+	delivery := f.repo.delivery(1)
+	if delivery.Status != domain.DeliveryFailed || delivery.Attempts != cfg.MaxAttempts {
+		t.Errorf("delivery = %+v, want failed after %d attempts", delivery, cfg.MaxAttempts)
+	}
+	if int(calls.Load()) != cfg.MaxAttempts {
+		t.Errorf("receiver got %d requests, want %d", calls.Load(), cfg.MaxAttempts)
+	}
+	if !strings.HasPrefix(delivery.LastError, "500 Internal Server Error") {
+		t.Errorf("LastError = %q", delivery.LastError)
+	}
+}
+
+func TestWorkerBackoffIsCapped(t *testing.T) {
+	cfg := testWorkerConfig()
+	w := NewWorker(slog.New(slog.NewTextHandler(io.Discard, nil)), newMemoryRepository(), cfg)
+	for _, attempts := range []int{8, 20, 64} {
+		if d := w.backoff(attempts); d < cfg.MaxBackoff || d > cfg.MaxBackoff+cfg.MaxBackoff/10 {
+			t.Errorf("backoff(%d) = %v, want %v plus up to 10%% jitter", attempts, d, cfg.MaxBackoff)
+		}
+	}
+}
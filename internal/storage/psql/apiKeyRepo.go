@@ -0,0 +1,147 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type apiKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	TenantID   uuid.UUID `gorm:"type:uuid;not null"`
+	Name       string    `gorm:"not null"`
+	Prefix     string    `gorm:"not null"`
+	KeyHash    string    `gorm:"not null"`
+	Scopes     string    `gorm:"type:jsonb;not null"`
+	CreatedAt  time.Time `gorm:"default:now()"`
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func (apiKey) TableName() string {
+	return "api_keys"
+}
+
+func (row apiKey) toDomain() (*domain.APIKey, error) {
+	var scopes []domain.Scope
+	if err := json.Unmarshal([]byte(row.Scopes), &scopes); err != nil {
+		return nil, fmt.Errorf("invalid scopes of api key %s: %w", row.ID, err)
+	}
+	return &domain.APIKey{
+		ID:         row.ID,
+		TenantID:   row.TenantID,
+		Name:       row.Name,
+		Prefix:     row.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  row.CreatedAt,
+		ExpiresAt:  row.ExpiresAt,
+		RevokedAt:  row.RevokedAt,
+		LastUsedAt: row.LastUsedAt,
+	}, nil
+}
+
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey, hash string) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	assignTenant(ctx, &key.TenantID)
+	row := apiKey{
+		TenantID:  key.TenantID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   hash,
+		Scopes:    string(scopes),
+		ExpiresAt: key.ExpiresAt,
+	}
+	if err := conn(ctx, r.db).Create(&row).Error; err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	key.ID = row.ID
+	key.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *APIKeyRepository) APIKey(ctx context.Context, keyID uuid.UUID) (*domain.APIKey, error) {
+	return r.first(forTenant(ctx, conn(ctx, r.db)).Where("id = ?", keyID))
+}
+
+func (r *APIKeyRepository) APIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	key, err := r.first(conn(ctx, r.db).Where("key_hash = ?", hash))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *APIKeyRepository) first(query *gorm.DB) (*domain.APIKey, error) {
+	var row apiKey
+	err := query.First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain()
+}
+
+func (r *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	var rows []apiKey
+	if err := forTenant(ctx, conn(ctx, r.db)).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]*domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		key, err := row.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error {
+	result := forTenant(ctx, conn(ctx, r.db).Model(&apiKey{})).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.APIKey(ctx, keyID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) ExpireAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error {
+	result := forTenant(ctx, conn(ctx, r.db).Model(&apiKey{})).
+		Where("id = ?", keyID).
+		Where("expires_at IS NULL OR expires_at > ?", at).
+		Update("expires_at", at)
+	return result.Error
+}
+
+func (r *APIKeyRepository) TouchAPIKey(ctx context.Context, keyID uuid.UUID, at time.Time) error {
+	return conn(ctx, r.db).Model(&apiKey{}).Where("id = ?", keyID).Update("last_used_at", at).Error
+}
@@ -0,0 +1,130 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+)
+
+type subscriptionAudit struct {
+	ID             int64     `gorm:"primaryKey"`
+	TenantID       uuid.UUID `gorm:"type:uuid;not null"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null"`
+	Operation      string    `gorm:"not null"`
+	Actor          string    `gorm:"not null"`
+	RequestID      *string
+	Before         *string   `gorm:"type:jsonb"`
+	After          *string   `gorm:"type:jsonb"`
+	CreatedAt      time.Time `gorm:"default:now()"`
+}
+
+func (subscriptionAudit) TableName() string {
+	return "subscription_audit"
+}
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) AppendAudit(ctx context.Context, entries ...domain.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	rows := make([]subscriptionAudit, len(entries))
+	for i, entry := range entries {
+		row := subscriptionAudit{
+			SubscriptionID: entry.SubscriptionID,
+			Operation:      string(entry.Operation),
+			Actor:          entry.Actor,
+		}
+		// The tenant of the subscription, unscoped jobs such as the purge write entries
+		// of every tenant.
+		if entry.After != nil {
+			row.TenantID = entry.After.TenantID
+		} else if entry.Before != nil {
+			row.TenantID = entry.Before.TenantID
+		}
+		assignTenant(ctx, &row.TenantID)
+		if entry.RequestID != "" {
+			row.RequestID = &entry.RequestID
+		}
+		var err error
+		if row.Before, err = snapshot(entry.Before); err != nil {
+			return err
+		}
+		if row.After, err = snapshot(entry.After); err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+	if err := conn(ctx, r.db).CreateInBatches(rows, 100).Error; err != nil {
+		return fmt.Errorf("failed to append audit entries: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) History(ctx context.Context, subscriptionID uuid.UUID, offset, limit int) ([]domain.AuditEntry, int64, error) {
+	query := forTenant(ctx, conn(ctx, r.db).Model(&subscriptionAudit{})).Where("subscription_id = ?", subscriptionID)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []subscriptionAudit
+	err := query.Order("id").Offset(offset).Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := make([]domain.AuditEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = domain.AuditEntry{
+			ID:             row.ID,
+			TenantID:       row.TenantID,
+			SubscriptionID: row.SubscriptionID,
+			Operation:      domain.AuditOperation(row.Operation),
+			Actor:          row.Actor,
+			CreatedAt:      row.CreatedAt,
+		}
+		if row.RequestID != nil {
+			entries[i].RequestID = *row.RequestID
+		}
+		if entries[i].Before, err = restore(row.Before); err != nil {
+			return nil, 0, err
+		}
+		if entries[i].After, err = restore(row.After); err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, total, nil
+}
+
+func snapshot(subscription *domain.Subscription) (*string, error) {
+	if subscription == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	s := string(raw)
+	return &s, nil
+}
+
+func restore(raw *string) (*domain.Subscription, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var subscription domain.Subscription
+	if err := json.Unmarshal([]byte(*raw), &subscription); err != nil {
+		return nil, fmt.Errorf("failed to decode audit snapshot: %w", err)
+	}
+	return &subscription, nil
+}
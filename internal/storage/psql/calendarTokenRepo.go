@@ -0,0 +1,67 @@
+package psql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type calendarToken struct {
+	TenantID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TokenHash string    `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (calendarToken) TableName() string {
+	return "calendar_tokens"
+}
+
+type CalendarTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarTokenRepository(db *gorm.DB) *CalendarTokenRepository {
+	return &CalendarTokenRepository{db: db}
+}
+
+func (r *CalendarTokenRepository) SaveCalendarToken(ctx context.Context, token *domain.CalendarToken, hash string) error {
+	assignTenant(ctx, &token.TenantID)
+	token.CreatedAt = time.Now()
+	row := calendarToken{
+		TenantID:  token.TenantID,
+		UserID:    token.UserID,
+		TokenHash: hash,
+		CreatedAt: token.CreatedAt,
+	}
+	err := conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_hash", "created_at"}),
+	}).Create(&row).Error
+	if err != nil {
+		return fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return nil
+}
+
+func (r *CalendarTokenRepository) CalendarTokenByHash(ctx context.Context, hash string) (*domain.CalendarToken, error) {
+	var row calendarToken
+	err := conn(ctx, r.db).Where("token_hash = ?", hash).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.CalendarToken{TenantID: row.TenantID, UserID: row.UserID, CreatedAt: row.CreatedAt}, nil
+}
+
+func (r *CalendarTokenRepository) DeleteCalendarToken(ctx context.Context, userID uuid.UUID) error {
+	return forTenant(ctx, conn(ctx, r.db)).Where("user_id = ?", userID).Delete(&calendarToken{}).Error
+}
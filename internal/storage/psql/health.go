@@ -0,0 +1,37 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// HealthChecker reports whether the database is usable for readiness probes.
+type HealthChecker struct {
+	db *gorm.DB
+}
+
+func NewHealthChecker(db *gorm.DB) *HealthChecker {
+	return &HealthChecker{db: db}
+}
+
+func (h *HealthChecker) Ping(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get db: %w", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// MigrationVersion returns the schema version recorded by golang-migrate. Dirty is set
+// when the last migration failed halfway.
+func (h *HealthChecker) MigrationVersion(ctx context.Context) (version uint, dirty bool, err error) {
+	err = h.db.WithContext(ctx).
+		Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").
+		Row().Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return version, dirty, nil
+}
@@ -0,0 +1,100 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyKey struct {
+	Key             string    `gorm:"primaryKey"`
+	RequestHash     string    `gorm:"not null"`
+	StatusCode      *int      `gorm:""`
+	ResponseHeaders *string   `gorm:"type:jsonb"`
+	ResponseBody    []byte    `gorm:""`
+	CreatedAt       time.Time `gorm:"default:now()"`
+}
+
+func (idempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+type IdempotencyRepository struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+func NewIdempotencyRepository(db *gorm.DB, ttl time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, ttl: ttl}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (*domain.IdempotencyRecord, bool, error) {
+	db := conn(ctx, r.db)
+	expired := db.Where("key = ? AND created_at < ?", key, time.Now().Add(-r.ttl)).Delete(&idempotencyKey{})
+	if expired.Error != nil {
+		return nil, false, fmt.Errorf("failed to drop expired idempotency key: %w", expired.Error)
+	}
+
+	row := idempotencyKey{Key: key, RequestHash: requestHash}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&row)
+	if result.Error != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		record, err := row.toDomain()
+		return record, true, err
+	}
+
+	var existing idempotencyKey
+	if err := db.Where("key = ?", key).First(&existing).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	record, err := existing.toDomain()
+	return record, false, err
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, statusCode int, headers map[string]string, body []byte) error {
+	rawHeaders, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	return conn(ctx, r.db).Model(&idempotencyKey{}).
+		Where("key = ?", key).
+		Updates(map[string]interface{}{
+			"status_code":      statusCode,
+			"response_headers": string(rawHeaders),
+			"response_body":    body,
+		}).Error
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	return conn(ctx, r.db).Where("key = ? AND status_code IS NULL", key).Delete(&idempotencyKey{}).Error
+}
+
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result := conn(ctx, r.db).Where("created_at < ?", time.Now().Add(-r.ttl)).Delete(&idempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (k idempotencyKey) toDomain() (*domain.IdempotencyRecord, error) {
+	record := &domain.IdempotencyRecord{
+		Key:          k.Key,
+		RequestHash:  k.RequestHash,
+		ResponseBody: k.ResponseBody,
+		CreatedAt:    k.CreatedAt,
+	}
+	if k.StatusCode != nil {
+		record.StatusCode = *k.StatusCode
+	}
+	if k.ResponseHeaders != nil {
+		if err := json.Unmarshal([]byte(*k.ResponseHeaders), &record.ResponseHeaders); err != nil {
+			return nil, fmt.Errorf("failed to decode stored headers: %w", err)
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,85 @@
+package psql
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+var packagePath = reflect.TypeOf(SubscriptionRepository{}).PkgPath()
+
+// QueryObserver receives every query with the repository method that ran it, e.g.
+// "SubscriptionRepository.ListSubscription".
+type QueryObserver func(method string, duration time.Duration, err error)
+
+type callbackRegistrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// ObserveQueries registers GORM callbacks reporting every query of db to observe.
+func ObserveQueries(db *gorm.DB, observe QueryObserver) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet(queryStartKey, time.Now())
+	}
+	end := func(tx *gorm.DB) {
+		started, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		err := tx.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		observe(repositoryMethod(), time.Since(started.(time.Time)), err)
+	}
+	callbacks := db.Callback()
+	register := func(name string, before, after callbackRegistrar) error {
+		if err := before.Register("metrics:before_"+name, start); err != nil {
+			return err
+		}
+		return after.Register("metrics:after_"+name, end)
+	}
+	return errors.Join(
+		register("create", callbacks.Create().Before("*"), callbacks.Create().After("*")),
+		register("query", callbacks.Query().Before("*"), callbacks.Query().After("*")),
+		register("update", callbacks.Update().Before("*"), callbacks.Update().After("*")),
+		register("delete", callbacks.Delete().Before("*"), callbacks.Delete().After("*")),
+		register("row", callbacks.Row().Before("*"), callbacks.Row().After("*")),
+		register("raw", callbacks.Raw().Before("*"), callbacks.Raw().After("*")),
+	)
+}
+
+// repositoryMethod finds the repository method running the current query on the stack.
+// Private helpers such as missOrConflict are reported as the exported method calling them.
+func repositoryMethod() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	method := ""
+	for {
+		frame, more := frames.Next()
+		// e.g. ".../internal/storage/psql.(*SubscriptionRepository).ListSubscription"
+		name, ok := strings.CutPrefix(frame.Function, packagePath+".(*")
+		if ok {
+			typeName, methodName, _ := strings.Cut(name, ").")
+			methodName, _, _ = strings.Cut(methodName, ".")
+			if methodName != "" && methodName[0] >= 'A' && methodName[0] <= 'Z' {
+				method = typeName + "." + methodName
+			}
+		} else if method != "" || strings.HasPrefix(frame.Function, packagePath+".") {
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	if method == "" {
+		return "unknown"
+	}
+	return method
+}
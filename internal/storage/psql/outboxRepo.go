@@ -0,0 +1,147 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxEvent struct {
+	ID          int64      `gorm:"primaryKey"`
+	EventID     string     `gorm:"not null"`
+	EventType   string     `gorm:"not null"`
+	UserID      *uuid.UUID `gorm:"type:uuid"`
+	Payload     string     `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time  `gorm:"default:now()"`
+	PublishedAt *time.Time
+	Sequence    *int64
+}
+
+// relayLockKey serializes relays with an advisory lock, so that sequence numbers
+// are committed in the order they are taken.
+const relayLockKey = 7_314_002
+
+func (outboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) Append(ctx context.Context, events ...domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	rows := make([]outboxEvent, len(events))
+	for i, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		rows[i] = outboxEvent{
+			EventID:   event.ID,
+			EventType: string(event.Type),
+			Payload:   string(payload),
+		}
+		if event.Subscription != nil {
+			userID := event.Subscription.UserID
+			rows[i].UserID = &userID
+		}
+	}
+	// Event IDs are derived from the change, an event recorded twice is stored once.
+	err := conn(ctx, r.db).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 100).Error
+	if err != nil {
+		return fmt.Errorf("failed to append outbox events: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) ClaimPending(ctx context.Context, limit int) ([]domain.OutboxMessage, error) {
+	db := conn(ctx, r.db)
+	if err := db.Exec("SELECT pg_advisory_xact_lock(?)", relayLockKey).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	var rows []outboxEvent
+	err := db.Where("published_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	messages := make([]domain.OutboxMessage, len(rows))
+	for i, row := range rows {
+		messages[i].ID = row.ID
+		if err := json.Unmarshal([]byte(row.Payload), &messages[i].Event); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox event %d: %w", row.ID, err)
+		}
+	}
+	return messages, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	db := conn(ctx, r.db)
+	var sequences []int64
+	err := db.Raw("SELECT nextval('outbox_events_sequence') FROM generate_series(1, ?)", len(ids)).Scan(&sequences).Error
+	if err != nil {
+		return fmt.Errorf("failed to allocate event sequences: %w", err)
+	}
+	values := make([]string, len(ids))
+	args := make([]interface{}, 0, 2*len(ids))
+	for i, id := range ids {
+		values[i] = "(?::bigint, ?::bigint)"
+		args = append(args, id, sequences[i])
+	}
+	query := `UPDATE outbox_events SET published_at = now(), sequence = v.sequence
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v(id, sequence)
+		WHERE outbox_events.id = v.id`
+	if err := db.Exec(query, args...).Error; err != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+	return nil
+}
+
+func (r *OutboxRepository) LastSequence(ctx context.Context) (int64, error) {
+	var last int64
+	err := conn(ctx, r.db).Model(&outboxEvent{}).Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error
+	return last, err
+}
+
+func (r *OutboxRepository) EventsAfter(ctx context.Context, after int64, userID *uuid.UUID, limit int) ([]domain.StreamEvent, error) {
+	var rows []outboxEvent
+	query := conn(ctx, r.db).Where("sequence > ?", after).Order("sequence").Limit(limit)
+	if userID != nil {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	events := make([]domain.StreamEvent, len(rows))
+	for i, row := range rows {
+		events[i].Sequence = *row.Sequence
+		if err := json.Unmarshal([]byte(row.Payload), &events[i].Event); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox event %d: %w", row.ID, err)
+		}
+	}
+	return events, nil
+}
+
+func (r *OutboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("published_at < ?", before).Delete(&outboxEvent{})
+	return result.RowsAffected, result.Error
+}
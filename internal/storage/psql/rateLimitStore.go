@@ -0,0 +1,39 @@
+package psql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/ratelimit"
+	"gorm.io/gorm"
+)
+
+// RateLimitStore keeps rate limit buckets in Postgres, so all instances share them.
+type RateLimitStore struct {
+	db *gorm.DB
+}
+
+func NewRateLimitStore(db *gorm.DB) *RateLimitStore {
+	return &RateLimitStore{db: db}
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	if err := limit.Validate(); err != nil {
+		return ratelimit.Result{}, err
+	}
+	var remaining float64
+	var allowed bool
+	err := conn(ctx, s.db).
+		Raw("SELECT remaining, allowed FROM rate_limit_take(?, ?, ?)", key, limit.Rate, float64(limit.Burst)).
+		Row().Scan(&remaining, &allowed)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return ratelimit.NewResult(limit, remaining, allowed), nil
+}
+
+// DeleteFull removes buckets that have refilled, they are recreated full on the next request.
+func (s *RateLimitStore) DeleteFull(ctx context.Context) (int64, error) {
+	result := conn(ctx, s.db).Exec("DELETE FROM rate_limit_buckets WHERE full_at < now()")
+	return result.RowsAffected, result.Error
+}
@@ -4,67 +4,174 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// month_year is stored as MM-YYYY text, so periods are compared as dates rather than strings.
+const (
+	startsBefore = "to_date(start_date, 'MM-YYYY') <= to_date(?, 'MM-YYYY')"
+	endsAfter    = "(end_date IS NULL OR to_date(end_date, 'MM-YYYY') >= to_date(?, 'MM-YYYY'))"
+	notDeleted   = "deleted_at IS NULL"
 )
 
 type SubscriptionRepository struct {
 	db *gorm.DB
 }
 
-var ErrSubscriptNotFound = errors.New("Subscript not found")
+var (
+	ErrSubscriptNotFound = errors.New("Subscript not found")
+	ErrVersionConflict   = errors.New("subscription was modified by another request")
+)
 
 func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
 	return &SubscriptionRepository{db: db}
 }
 
 func (r *SubscriptionRepository) SaveSubscription(ctx context.Context, subscription *domain.Subscription) (uuid.UUID, error) {
-	result := r.db.WithContext(ctx).Create(&subscription)
+	assignTenant(ctx, &subscription.TenantID)
+	result := conn(ctx, r.db).Create(&subscription)
 	return subscription.ID, result.Error
 }
 
-func (r *SubscriptionRepository) Subscription(ctx context.Context, subscriptionID uuid.UUID) (*domain.Subscription, error) {
+func (r *SubscriptionRepository) SaveSubscriptions(ctx context.Context, subscriptions []*domain.Subscription) error {
+	for _, subscription := range subscriptions {
+		assignTenant(ctx, &subscription.TenantID)
+	}
+	return conn(ctx, r.db).CreateInBatches(subscriptions, 100).Error
+}
+
+func (r *SubscriptionRepository) Subscription(ctx context.Context, subscriptionID uuid.UUID, includeDeleted bool) (*domain.Subscription, error) {
 	var subscription *domain.Subscription
-	err := r.db.Where("id = ?", subscriptionID).First(&subscription).Error
+	query := forTenant(ctx, conn(ctx, r.db)).Where("id = ?", subscriptionID)
+	if !includeDeleted {
+		query = query.Where(notDeleted)
+	}
+	err := query.First(&subscription).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrSubscriptNotFound
 	}
 	return subscription, err
 }
 
-func (r *SubscriptionRepository) DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID) error {
-	err := r.db.WithContext(ctx).Where("id = ?", subscriptionID).Delete(&domain.Subscription{}).Error
-	if errors.Is(err, ErrSubscriptNotFound) {
-		return ErrSubscriptNotFound
+// DeleteSubscription marks the row deleted, bumps its version and returns it. The row
+// stays until PurgeDeleted, so it still counts for past periods with includeDeleted.
+func (r *SubscriptionRepository) DeleteSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*domain.Subscription, error) {
+	return r.setDeleted(ctx, subscriptionID, version, true)
+}
+
+// RestoreSubscription clears the deletion mark of a soft-deleted row and returns it.
+func (r *SubscriptionRepository) RestoreSubscription(ctx context.Context, subscriptionID uuid.UUID, version int) (*domain.Subscription, error) {
+	return r.setDeleted(ctx, subscriptionID, version, false)
+}
+
+func (r *SubscriptionRepository) setDeleted(ctx context.Context, subscriptionID uuid.UUID, version int, deleted bool) (*domain.Subscription, error) {
+	var updated domain.Subscription
+	query := forTenant(ctx, conn(ctx, r.db).Model(&updated)).
+		Clauses(clause.Returning{}).
+		Where("id = ?", subscriptionID)
+	deletedAt := gorm.Expr("now()")
+	if deleted {
+		query = query.Where(notDeleted)
+	} else {
+		query = query.Where("deleted_at IS NOT NULL")
+		deletedAt = gorm.Expr("NULL")
+	}
+	if version != domain.AnyVersion {
+		query = query.Where("version = ?", version)
 	}
-	return err
+	result := query.Updates(map[string]interface{}{
+		"deleted_at": deletedAt,
+		"version":    gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return &updated, nil
+	}
+	if deleted {
+		return nil, r.missOrConflict(ctx, subscriptionID)
+	}
+	stored, err := r.Subscription(ctx, subscriptionID, true)
+	if err != nil {
+		return nil, err
+	}
+	if stored.DeletedAt == nil {
+		return nil, domain.ErrNotDeleted
+	}
+	return nil, ErrVersionConflict
 }
 
+func (r *SubscriptionRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) ([]*domain.Subscription, error) {
+	var purged []*domain.Subscription
+	err := conn(ctx, r.db).Clauses(clause.Returning{}).
+		Where("id IN (?)", forTenant(ctx, conn(ctx, r.db).Model(&domain.Subscription{})).
+			Select("id").
+			Where("deleted_at < ?", before).
+			Order("deleted_at").
+			Limit(limit)).
+		Delete(&purged).Error
+	return purged, err
+}
+
+// UpdateSubscription writes every column only if the stored version still equals subscription.Version,
+// and refreshes subscription with the stored row on success.
 func (r *SubscriptionRepository) UpdateSubscription(ctx context.Context, subscription *domain.Subscription) error {
-	result := r.db.WithContext(ctx).Model(&domain.Subscription{}).
+	var updated domain.Subscription
+	query := forTenant(ctx, conn(ctx, r.db).Model(&updated)).
+		Clauses(clause.Returning{}).
 		Where("id = ?", subscription.ID).
-		Omit("id").
-		Updates(&subscription)
+		Where(notDeleted)
+	if subscription.Version != domain.AnyVersion {
+		query = query.Where("version = ?", subscription.Version)
+	}
+	result := query.Updates(map[string]interface{}{
+		"service_name": subscription.ServiceName,
+		"price":        subscription.Price,
+		"user_id":      subscription.UserID,
+		"start_date":   subscription.StartDate,
+		"end_date":     subscription.EndDate,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.missOrConflict(ctx, subscription.ID)
+	}
+	*subscription = updated
+	return nil
+}
 
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+func (r *SubscriptionRepository) missOrConflict(ctx context.Context, subscriptionID uuid.UUID) error {
+	var count int64
+	if err := forTenant(ctx, conn(ctx, r.db).Model(&domain.Subscription{})).Where("id = ?", subscriptionID).Where(notDeleted).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
 		return ErrSubscriptNotFound
 	}
-	return result.Error
+	return ErrVersionConflict
 }
-func (r *SubscriptionRepository) ListSubscription(ctx context.Context, offset, limit int) ([]*domain.Subscription, error) {
+
+func (r *SubscriptionRepository) ListSubscription(ctx context.Context, filter domain.SubscriptionFilter, offset, limit int) ([]*domain.Subscription, error) {
 	var subscriptions []*domain.Subscription
-	err := r.db.WithContext(ctx).Offset(offset).Limit(limit).Model(&domain.Subscription{}).Scan(&subscriptions).Error
+	query := filtered(ctx, conn(ctx, r.db).Offset(offset).Limit(limit).Model(&domain.Subscription{}), filter)
+	err := query.Scan(&subscriptions).Error
 	return subscriptions, err
 }
 
-func (r *SubscriptionRepository) TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate domain.MonthYear) ([]domain.Subscription, error) {
+func (r *SubscriptionRepository) TotalCost(ctx context.Context, userID *uuid.UUID, serviceName *string, startDate, endDate domain.MonthYear, includeDeleted bool) ([]domain.Subscription, error) {
 	var subscriptions []domain.Subscription
 
-	query := r.db.WithContext(ctx).Model(&domain.Subscription{}).
-		Where("start_date <= ?", endDate).
-		Where("(end_date IS NULL OR end_date >= ?)", startDate)
+	query := forTenant(ctx, conn(ctx, r.db).Model(&domain.Subscription{})).
+		Where(startsBefore, endDate).
+		Where(endsAfter, startDate)
 
 	if userID != nil {
 		query = query.Where("user_id = ?", userID)
@@ -72,6 +179,9 @@ func (r *SubscriptionRepository) TotalCost(ctx context.Context, userID *uuid.UUI
 	if serviceName != nil {
 		query = query.Where("service_name = ?", serviceName)
 	}
+	if !includeDeleted {
+		query = query.Where(notDeleted)
+	}
 	err := query.Scan(&subscriptions).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate total cost: %w", err)
@@ -80,8 +190,71 @@ func (r *SubscriptionRepository) TotalCost(ctx context.Context, userID *uuid.UUI
 	return subscriptions, nil
 }
 
-func (r *SubscriptionRepository) Count(ctx context.Context) (int64, error) {
+func (r *SubscriptionRepository) StreamSubscriptions(ctx context.Context, filter domain.SubscriptionFilter, fn func(*domain.Subscription) error) error {
+	db := conn(ctx, r.db)
+	rows, err := filtered(ctx, db.Model(&domain.Subscription{}).Order("id"), filter).Rows()
+	if err != nil {
+		return fmt.Errorf("failed to stream subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var subscription domain.Subscription
+		if err := db.ScanRows(rows, &subscription); err != nil {
+			return fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		if err := fn(&subscription); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *SubscriptionRepository) Count(ctx context.Context, filter domain.SubscriptionFilter) (int64, error) {
 	var count int64
-	result := r.db.Model(&domain.Subscription{}).Count(&count)
+	result := filtered(ctx, conn(ctx, r.db).Model(&domain.Subscription{}), filter).Count(&count)
 	return count, result.Error
 }
+
+// TenantTotals is the number and monthly price of the subscriptions active in a month.
+type TenantTotals struct {
+	TenantID      uuid.UUID
+	Subscriptions int64
+	MonthlySpend  int64
+}
+
+// ActiveTotals sums the subscriptions active in month by tenant.
+func (r *SubscriptionRepository) ActiveTotals(ctx context.Context, month domain.MonthYear) ([]TenantTotals, error) {
+	var totals []TenantTotals
+	err := forTenant(ctx, conn(ctx, r.db).Model(&domain.Subscription{})).
+		Select("tenant_id, count(*) AS subscriptions, COALESCE(sum(price), 0) AS monthly_spend").
+		Where(startsBefore, month).
+		Where(endsAfter, month).
+		Where(notDeleted).
+		Group("tenant_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum active subscriptions: %w", err)
+	}
+	return totals, nil
+}
+
+func filtered(ctx context.Context, query *gorm.DB, filter domain.SubscriptionFilter) *gorm.DB {
+	query = forTenant(ctx, query)
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.ServiceName != nil {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if filter.ActiveTo != nil {
+		query = query.Where(startsBefore, filter.ActiveTo)
+	}
+	if filter.ActiveFrom != nil {
+		query = query.Where(endsAfter, filter.ActiveFrom)
+	}
+	if !filter.IncludeDeleted {
+		query = query.Where(notDeleted)
+	}
+	return query
+}
@@ -0,0 +1,60 @@
+package psql
+
+import (
+	"errors"
+
+	"github.com/immxrtalbeast/subscription-aggregator/internal/lib/tracing"
+	"gorm.io/gorm"
+)
+
+const querySpanKey = "tracing:query_span"
+
+// TraceQueries registers GORM callbacks recording a span for every query of db, named
+// after the repository method that ran it.
+func TraceQueries(db *gorm.DB) error {
+	start := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			_, span := tracing.Start(tx.Statement.Context, repositoryMethod(),
+				tracing.WithKind(tracing.KindClient),
+				tracing.WithAttributes(
+					tracing.String("db.system", "postgresql"),
+					tracing.String("db.operation", operation),
+				),
+			)
+			tx.InstanceSet(querySpanKey, span)
+		}
+	}
+	end := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(querySpanKey)
+		if !ok {
+			return
+		}
+		span := value.(*tracing.Span)
+		span.SetAttributes(
+			tracing.String("db.statement", tx.Statement.SQL.String()),
+			tracing.Int("db.rows_affected", int(tx.Statement.RowsAffected)),
+		)
+		if tx.Statement.Table != "" {
+			span.SetAttributes(tracing.String("db.sql.table", tx.Statement.Table))
+		}
+		if !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+		}
+		span.End()
+	}
+	callbacks := db.Callback()
+	register := func(name string, before, after callbackRegistrar) error {
+		if err := before.Register("tracing:before_"+name, start(name)); err != nil {
+			return err
+		}
+		return after.Register("tracing:after_"+name, end)
+	}
+	return errors.Join(
+		register("create", callbacks.Create().Before("*"), callbacks.Create().After("*")),
+		register("query", callbacks.Query().Before("*"), callbacks.Query().After("*")),
+		register("update", callbacks.Update().Before("*"), callbacks.Update().After("*")),
+		register("delete", callbacks.Delete().Before("*"), callbacks.Delete().After("*")),
+		register("row", callbacks.Row().Before("*"), callbacks.Row().After("*")),
+		register("raw", callbacks.Raw().Before("*"), callbacks.Raw().After("*")),
+	)
+}
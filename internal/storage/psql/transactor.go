@@ -0,0 +1,52 @@
+package psql
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction stored in ctx by Transactor, or db outside of one.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// forTenant limits query to the tenant of ctx, queries without one see every tenant.
+func forTenant(ctx context.Context, query *gorm.DB) *gorm.DB {
+	if tenantID, ok := domain.TenantFromContext(ctx); ok {
+		return query.Where("tenant_id = ?", tenantID)
+	}
+	return query
+}
+
+// assignTenant moves a new row to the tenant of ctx. Rows created without a tenant in
+// ctx keep theirs, the zero value being the default tenant.
+func assignTenant(ctx context.Context, tenantID *uuid.UUID) {
+	if tenant, ok := domain.TenantFromContext(ctx); ok {
+		*tenantID = tenant
+	}
+}
@@ -0,0 +1,240 @@
+package psql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/immxrtalbeast/subscription-aggregator/internal/domain"
+	"gorm.io/gorm"
+)
+
+var ErrWebhookNotFound = errors.New("webhook endpoint not found")
+
+type webhookEndpoint struct {
+	ID         uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;not null"`
+	URL        string     `gorm:"not null"`
+	Secret     string     `gorm:"not null"`
+	EventTypes string     `gorm:"type:jsonb;not null"`
+	UserID     *uuid.UUID `gorm:"type:uuid"`
+	Active     bool       `gorm:"not null"`
+	CreatedAt  time.Time  `gorm:"default:now()"`
+}
+
+func (webhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+type webhookDelivery struct {
+	ID             int64     `gorm:"primaryKey"`
+	EndpointID     uuid.UUID `gorm:"type:uuid;not null"`
+	EventID        string    `gorm:"not null"`
+	EventType      string    `gorm:"not null"`
+	Payload        string    `gorm:"type:jsonb;not null"`
+	Status         string    `gorm:"not null"`
+	Attempts       int       `gorm:"not null"`
+	NextAttemptAt  time.Time `gorm:"not null"`
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      time.Time `gorm:"default:now()"`
+	DeliveredAt    *time.Time
+}
+
+func (webhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	eventTypes := endpoint.EventTypes
+	if eventTypes == nil {
+		eventTypes = []domain.EventType{}
+	}
+	rawTypes, err := json.Marshal(eventTypes)
+	if err != nil {
+		return err
+	}
+	assignTenant(ctx, &endpoint.TenantID)
+	row := webhookEndpoint{
+		TenantID:   endpoint.TenantID,
+		URL:        endpoint.URL,
+		Secret:     endpoint.Secret,
+		EventTypes: string(rawTypes),
+		UserID:     endpoint.UserID,
+		Active:     endpoint.Active,
+	}
+	if err := conn(ctx, r.db).Create(&row).Error; err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	endpoint.ID = row.ID
+	endpoint.EventTypes = eventTypes
+	endpoint.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *WebhookRepository) Endpoint(ctx context.Context, endpointID uuid.UUID) (*domain.WebhookEndpoint, error) {
+	var row webhookEndpoint
+	err := forTenant(ctx, conn(ctx, r.db)).Where("id = ?", endpointID).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain()
+}
+
+func (r *WebhookRepository) ListEndpoints(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	var rows []webhookEndpoint
+	if err := forTenant(ctx, conn(ctx, r.db)).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	endpoints := make([]*domain.WebhookEndpoint, 0, len(rows))
+	for _, row := range rows {
+		endpoint, err := row.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, endpointID uuid.UUID) error {
+	result := forTenant(ctx, conn(ctx, r.db)).Where("id = ?", endpointID).Delete(&webhookEndpoint{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Enqueue(ctx context.Context, event domain.Event, payload []byte) (int64, error) {
+	var userID *uuid.UUID
+	tenantID := domain.DefaultTenant
+	if event.Subscription != nil {
+		userID = &event.Subscription.UserID
+		tenantID = event.Subscription.TenantID
+	}
+	result := conn(ctx, r.db).Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload)
+		SELECT id, ?, ?, ?::jsonb FROM webhook_endpoints
+		WHERE active AND tenant_id = ?
+		  AND (event_types = '[]'::jsonb OR event_types @> jsonb_build_array(?::text))
+		  AND (user_id IS NULL OR user_id = ?)
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING`,
+		event.ID, string(event.Type), string(payload), tenantID, string(event.Type), userID,
+	)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *WebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error) {
+	var rows []webhookDelivery
+	err := conn(ctx, r.db).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = now() + ?::interval
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		fmt.Sprintf("%d milliseconds", lease.Milliseconds()), string(domain.DeliveryPending), limit,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	deliveries := make([]*domain.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = row.toDomain()
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	var lastError *string
+	if delivery.LastError != "" {
+		lastError = &delivery.LastError
+	}
+	return conn(ctx, r.db).Model(&webhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":           string(delivery.Status),
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       lastError,
+			"delivered_at":     delivery.DeliveredAt,
+		}).Error
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, endpointID uuid.UUID, offset, limit int) ([]*domain.WebhookDelivery, int64, error) {
+	db := conn(ctx, r.db)
+	var total int64
+	if err := db.Model(&webhookDelivery{}).Where("endpoint_id = ?", endpointID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []webhookDelivery
+	err := db.Where("endpoint_id = ?", endpointID).Order("id DESC").Offset(offset).Limit(limit).Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	deliveries := make([]*domain.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = row.toDomain()
+	}
+	return deliveries, total, nil
+}
+
+func (e webhookEndpoint) toDomain() (*domain.WebhookEndpoint, error) {
+	endpoint := &domain.WebhookEndpoint{
+		ID:        e.ID,
+		TenantID:  e.TenantID,
+		URL:       e.URL,
+		Secret:    e.Secret,
+		UserID:    e.UserID,
+		Active:    e.Active,
+		CreatedAt: e.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(e.EventTypes), &endpoint.EventTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode event types: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (d webhookDelivery) toDomain() *domain.WebhookDelivery {
+	delivery := &domain.WebhookDelivery{
+		ID:             d.ID,
+		EndpointID:     d.EndpointID,
+		EventID:        d.EventID,
+		EventType:      domain.EventType(d.EventType),
+		Payload:        json.RawMessage(d.Payload),
+		Status:         domain.DeliveryStatus(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	if d.LastError != nil {
+		delivery.LastError = *d.LastError
+	}
+	return delivery
+}
@@ -0,0 +1,5 @@
+CREATE SEQUENCE IF NOT EXISTS outbox_events_sequence;
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS sequence BIGINT NULL UNIQUE;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_user_sequence ON outbox_events(user_id, sequence) WHERE sequence IS NOT NULL;
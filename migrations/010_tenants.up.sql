@@ -0,0 +1,18 @@
+-- Existing rows belong to the default tenant, the nil UUID.
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE webhook_endpoints ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+DROP INDEX IF EXISTS idx_subscriptions_user_id;
+CREATE INDEX IF NOT EXISTS idx_subscriptions_tenant_user ON subscriptions(tenant_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_tenant ON webhook_endpoints(tenant_id);
+
+-- Second line of defense for roles other than the table owner: transactions made for a
+-- tenant set app.tenant_id and only see its rows. Sessions without the setting, such as
+-- background jobs, are not limited.
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY subscriptions_tenant_isolation ON subscriptions
+    USING (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    );
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(512) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    full_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
+
+-- rate_limit_take refills the bucket of p_key and takes a request from it, concurrent
+-- calls for one key are serialized by the row lock.
+CREATE OR REPLACE FUNCTION rate_limit_take(p_key TEXT, p_rate DOUBLE PRECISION, p_burst DOUBLE PRECISION)
+RETURNS TABLE (remaining DOUBLE PRECISION, allowed BOOLEAN) AS $$
+DECLARE
+    available DOUBLE PRECISION;
+BEGIN
+    INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES (p_key, p_burst, clock_timestamp())
+    ON CONFLICT (key) DO NOTHING;
+
+    SELECT LEAST(p_burst, b.tokens + EXTRACT(EPOCH FROM clock_timestamp() - b.updated_at) * p_rate)
+    INTO available
+    FROM rate_limit_buckets b
+    WHERE b.key = p_key
+    FOR UPDATE;
+
+    allowed := available >= 1;
+    IF allowed THEN
+        available := available - 1;
+    END IF;
+
+    UPDATE rate_limit_buckets b
+    SET tokens = available,
+        updated_at = clock_timestamp(),
+        full_at = clock_timestamp() + make_interval(secs => (p_burst - available) / p_rate)
+    WHERE b.key = p_key;
+
+    remaining := available;
+    RETURN NEXT;
+END;
+$$ LANGUAGE plpgsql;
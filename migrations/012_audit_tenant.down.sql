@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_subscription_audit_tenant_subscription;
+CREATE INDEX IF NOT EXISTS idx_subscription_audit_subscription ON subscription_audit(subscription_id, id);
+
+ALTER TABLE subscription_audit DROP COLUMN IF EXISTS tenant_id;
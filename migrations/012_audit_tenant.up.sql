@@ -0,0 +1,13 @@
+-- Audit entries belong to the tenant of their subscription, so history stays isolated
+-- after the subscription is purged. Entries written before migration 010 have no
+-- tenant in their snapshots and stay in the default tenant.
+ALTER TABLE subscription_audit ADD COLUMN IF NOT EXISTS tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE subscription_audit DISABLE TRIGGER subscription_audit_append_only;
+UPDATE subscription_audit
+SET tenant_id = (COALESCE(after, before)->>'tenant_id')::uuid
+WHERE COALESCE(after, before)->>'tenant_id' IS NOT NULL;
+ALTER TABLE subscription_audit ENABLE TRIGGER subscription_audit_append_only;
+
+DROP INDEX IF EXISTS idx_subscription_audit_subscription;
+CREATE INDEX IF NOT EXISTS idx_subscription_audit_tenant_subscription ON subscription_audit(tenant_id, subscription_id, id);
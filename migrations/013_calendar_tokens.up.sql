@@ -0,0 +1,9 @@
+-- One feed token per user, issuing a new one replaces the old token and deleting the
+-- row revokes it.
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    user_id UUID NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, user_id)
+);
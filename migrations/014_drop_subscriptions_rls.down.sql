@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+CREATE POLICY subscriptions_tenant_isolation ON subscriptions
+    USING (
+        COALESCE(current_setting('app.tenant_id', true), '') = ''
+        OR tenant_id = current_setting('app.tenant_id', true)::uuid
+    );
@@ -0,0 +1,5 @@
+-- The policy of migration 010 never isolated anything: the application connects as the
+-- table owner, which bypasses it, and sessions without app.tenant_id were allowed every
+-- row. Tenants are isolated by the tenant_id condition of every repository query.
+DROP POLICY IF EXISTS subscriptions_tenant_isolation ON subscriptions;
+ALTER TABLE subscriptions DISABLE ROW LEVEL SECURITY;